@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,40 +11,75 @@ import (
 	"flight-booking-system/internal/api"
 	"flight-booking-system/internal/config"
 	"flight-booking-system/internal/database"
+	"flight-booking-system/internal/logging"
+	"flight-booking-system/internal/models"
+	"flight-booking-system/internal/temporal/correlation"
+	"flight-booking-system/internal/temporal/workflows"
 
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/workflow"
 )
 
+var logger = logging.New("flight-booking-server")
+
 func main() {
 	// Load configuration
 	cfg := config.Load()
 
 	// Connect to database
-	db, err := database.NewDB(cfg.DatabaseDSN)
+	dbCtx, cancelDB := context.WithTimeout(context.Background(), cfg.StartupTimeout)
+	db, err := database.NewDB(dbCtx, cfg.DatabaseDSN, cfg.ReservationTimeout)
+	cancelDB()
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		logger.Error("failed to connect to database", err, nil)
+		os.Exit(1)
 	}
 	defer db.Close()
 
-	log.Println("Connected to database")
+	logger.Info("connected to database", nil)
 
 	// Connect to Temporal
-	temporalClient, err := client.Dial(client.Options{
-		HostPort: cfg.TemporalAddress,
-	})
+	dialCtx, cancelDial := context.WithTimeout(context.Background(), cfg.StartupTimeout)
+	temporalClient, err := dialTemporalClient(dialCtx, cfg)
+	cancelDial()
 	if err != nil {
-		log.Fatalf("Failed to create Temporal client: %v", err)
+		logger.Error("failed to create Temporal client", err, nil)
+		os.Exit(1)
 	}
 	defer temporalClient.Close()
 
-	log.Println("Connected to Temporal")
+	logger.Info("connected to Temporal", nil)
 
 	// Create API handler
-	handler := api.NewHandler(db, temporalClient)
+	sseHub := api.NewSSEHub()
+	handler := api.NewHandler(db, temporalClient, sseHub)
 
 	// Create router
 	router := api.NewRouter(handler)
 
+	// Start the relay that tails the seat_events outbox and publishes to
+	// the SSE hub backing GET /flights/{flightId}/events.
+	seatEventRelay := database.NewSeatEventRelay(db, sseHub, cfg.SeatEventRelayInterval)
+	seatEventRelayCtx, stopSeatEventRelay := context.WithCancel(context.Background())
+	defer stopSeatEventRelay()
+	go seatEventRelay.Run(seatEventRelayCtx)
+
+	// Start the background sweeper that purges expired idempotency keys
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	defer stopSweeper()
+	go runIdempotencySweeper(sweeperCtx, db, cfg.IdempotencyKeyTTL)
+
+	// Start the background sweeper that reclaims seat reservations left
+	// RESERVED past their window, in case the owning BookingWorkflow's own
+	// timer never gets a chance to release them itself.
+	reservationSweeper := database.NewReservationSweeper(db, cfg.ReservationSweepInterval, cfg.ReservationSweepBatchSize,
+		func(ctx context.Context, exp models.ExpiredReservation) {
+			notifyExpiredReservation(temporalClient, exp)
+		})
+	reservationSweeperCtx, stopReservationSweeper := context.WithCancel(context.Background())
+	defer stopReservationSweeper()
+	go reservationSweeper.Run(reservationSweeperCtx)
+
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         ":" + cfg.ServerPort,
@@ -57,9 +91,10 @@ func main() {
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Starting HTTP server on port %s", cfg.ServerPort)
+		logger.Info("starting HTTP server", map[string]interface{}{"port": cfg.ServerPort})
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			logger.Error("failed to start server", err, nil)
+			os.Exit(1)
 		}
 	}()
 
@@ -68,15 +103,92 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	logger.Info("shutting down server", nil)
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		logger.Error("server forced to shutdown", err, nil)
+		os.Exit(1)
+	}
+
+	logger.Info("server exited", nil)
+}
+
+// notifyExpiredReservation tells the order's BookingWorkflow to terminate,
+// since CleanupExpiredReservations already released its seats and marked it
+// EXPIRED directly in the database, and notifies the flight's waitlist
+// coordinator that seats just freed up -- the same two things BookingWorkflow
+// itself would do if its own reservation timer had fired first. Both calls
+// are best-effort: the workflow may already have reached a terminal state on
+// its own (the common case -- this sweep only matters when it didn't), and a
+// flight with no one waiting has no coordinator running to notify.
+func notifyExpiredReservation(temporalClient client.Client, exp models.ExpiredReservation) {
+	ctx := context.Background()
+
+	if exp.WorkflowID != "" {
+		if err := temporalClient.TerminateWorkflow(ctx, exp.WorkflowID, exp.RunID, "reservation expired"); err != nil {
+			logger.Info("failed to terminate expired reservation workflow", map[string]interface{}{
+				"order_id": exp.OrderID, "workflow_id": exp.WorkflowID, "error": err.Error(),
+			})
+		}
+	}
+
+	err := temporalClient.SignalWorkflow(ctx, workflows.WaitlistCoordinatorID(exp.FlightID), "",
+		workflows.SignalSeatsFreed, workflows.SeatsFreedSignal{Seats: exp.Seats})
+	if err != nil {
+		logger.Info("no waitlist coordinator to notify of expired reservation", map[string]interface{}{
+			"flight_id": exp.FlightID, "error": err.Error(),
+		})
 	}
+}
+
+// dialTemporalClient dials Temporal in a goroutine so the call can be
+// bounded by ctx -- client.Dial itself has no context parameter, so a
+// stuck dependency would otherwise hang startup indefinitely.
+func dialTemporalClient(ctx context.Context, cfg *config.Config) (client.Client, error) {
+	type dialResult struct {
+		client client.Client
+		err    error
+	}
+	resultCh := make(chan dialResult, 1)
+	go func() {
+		c, err := client.Dial(client.Options{
+			HostPort:           cfg.TemporalAddress,
+			ContextPropagators: []workflow.ContextPropagator{correlation.NewPropagator()},
+		})
+		resultCh <- dialResult{c, err}
+	}()
 
-	log.Println("Server exited")
+	select {
+	case res := <-resultCh:
+		return res.client, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// runIdempotencySweeper periodically purges idempotency_keys rows older
+// than ttl so the table doesn't grow unbounded, until ctx is cancelled.
+func runIdempotencySweeper(ctx context.Context, db *database.DB, ttl time.Duration) {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := db.PurgeExpiredIdempotencyKeys(ctx, ttl)
+			if err != nil {
+				logger.Error("idempotency key sweep failed", err, nil)
+				continue
+			}
+			if purged > 0 {
+				logger.Info("purged expired idempotency keys", map[string]interface{}{"count": purged})
+			}
+		}
+	}
 }
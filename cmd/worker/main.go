@@ -1,50 +1,96 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"sync/atomic"
+	"time"
 
 	"flight-booking-system/internal/config"
 	"flight-booking-system/internal/database"
+	"flight-booking-system/internal/logging"
+	"flight-booking-system/internal/notifications"
+	"flight-booking-system/internal/payments"
 	"flight-booking-system/internal/temporal/activities"
+	"flight-booking-system/internal/temporal/correlation"
 	"flight-booking-system/internal/temporal/workflows"
 
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
 	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
 )
 
+var logger = logging.New("flight-booking-worker")
+
 func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// ready flips true once the worker has started polling, and back to
+	// false as soon as a shutdown signal starts draining it, so /readyz
+	// reflects whether this process should still receive traffic.
+	var ready atomic.Bool
+	healthServer := newHealthServer(cfg.WorkerHealthPort, &ready)
+	go func() {
+		if err := healthServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("failed to start health server", err, nil)
+			os.Exit(1)
+		}
+	}()
+
 	// Connect to database
-	db, err := database.NewDB(cfg.DatabaseDSN)
+	dbCtx, cancelDB := context.WithTimeout(context.Background(), cfg.StartupTimeout)
+	db, err := database.NewDB(dbCtx, cfg.DatabaseDSN, cfg.ReservationTimeout)
+	cancelDB()
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		logger.Error("failed to connect to database", err, nil)
+		os.Exit(1)
 	}
 	defer db.Close()
 
-	log.Println("Connected to database")
+	logger.Info("connected to database", nil)
+
+	// Recover any 2PC transactions left PREPARED by a coordinator that
+	// crashed mid-flight, before this worker starts picking up new work.
+	recovered, err := db.RecoverInFlightTransactions(context.Background())
+	if err != nil {
+		logger.Error("failed to recover in-flight transactions", err, nil)
+		os.Exit(1)
+	}
+	if recovered > 0 {
+		logger.Info("recovered in-flight 2PC transactions", map[string]interface{}{"count": recovered})
+	}
 
 	// Connect to Temporal
-	temporalClient, err := client.Dial(client.Options{
-		HostPort: cfg.TemporalAddress,
-	})
+	dialCtx, cancelDial := context.WithTimeout(context.Background(), cfg.StartupTimeout)
+	temporalClient, err := dialTemporalClient(dialCtx, cfg)
+	cancelDial()
 	if err != nil {
-		log.Fatalf("Failed to create Temporal client: %v", err)
+		logger.Error("failed to create Temporal client", err, nil)
+		os.Exit(1)
 	}
 	defer temporalClient.Close()
 
-	log.Println("Connected to Temporal")
+	logger.Info("connected to Temporal", nil)
 
-	// Create worker
-	w := worker.New(temporalClient, "booking-task-queue", worker.Options{})
+	// Create worker. WorkerStopTimeout bounds how long Stop() will wait for
+	// in-flight activities to finish draining before it force-stops them.
+	// The logging interceptor logs start/end/duration/error uniformly for
+	// every activity registered below.
+	w := worker.New(temporalClient, "booking-task-queue", worker.Options{
+		WorkerStopTimeout: cfg.ShutdownGracePeriod,
+		Interceptors:      []interceptor.WorkerInterceptor{&activities.LoggingInterceptor{}},
+	})
 
 	// Register workflows
 	w.RegisterWorkflow(workflows.BookingWorkflow)
 	w.RegisterWorkflow(workflows.PaymentValidationWorkflow)
+	w.RegisterWorkflow(workflows.WaitlistCoordinatorWorkflow)
+	w.RegisterWorkflow(workflows.MultiFlightBookingWorkflow)
+	w.RegisterWorkflow(workflows.NotificationWorkflow)
 
 	// Register activities
 	seatActivities := activities.NewSeatActivities(db)
@@ -53,28 +99,142 @@ func main() {
 	w.RegisterActivity(seatActivities.UpdateSeats)
 	w.RegisterActivity(seatActivities.ConfirmSeats)
 
-	paymentActivities := activities.NewPaymentActivities(db)
-	w.RegisterActivity(paymentActivities.ValidatePayment)
+	paymentProvider, err := newPaymentProvider(cfg.PaymentProvider)
+	if err != nil {
+		logger.Error("failed to configure payment provider", err, nil)
+		os.Exit(1)
+	}
+	paymentActivities := activities.NewPaymentActivities(db, paymentProvider)
+	w.RegisterActivity(paymentActivities.AuthorizePayment)
+	w.RegisterActivity(paymentActivities.GetTransferStatus)
+	w.RegisterActivity(paymentActivities.CapturePayment)
+	w.RegisterActivity(paymentActivities.RefundPayment)
 	w.RegisterActivity(paymentActivities.UpdatePaymentRecord)
 
 	orderActivities := activities.NewOrderActivities(db)
 	w.RegisterActivity(orderActivities.UpdateOrderStatus)
-	w.RegisterActivity(orderActivities.SendConfirmation)
+	w.RegisterActivity(orderActivities.CreateOrder)
+	w.RegisterActivity(orderActivities.RecordTransitionLog)
+
+	emailNotifier, smsNotifier, webhookNotifier := notifications.NewEmailNotifier(), notifications.NewSMSNotifier(), notifications.NewWebhookNotifier()
+
+	notificationActivities := activities.NewNotificationActivities(db, emailNotifier, smsNotifier, webhookNotifier)
+	w.RegisterActivity(notificationActivities.SendEmailConfirmation)
+	w.RegisterActivity(notificationActivities.SendSMSConfirmation)
+	w.RegisterActivity(notificationActivities.SendWebhookConfirmation)
+
+	// Start the background retrier that requeues FAILED deliveries whose
+	// Temporal-level retries are exhausted, once their NextDeliveryAt
+	// arrives.
+	notificationRetrier := activities.NewNotificationRetrier(db, emailNotifier, smsNotifier, webhookNotifier, cfg.MaxDeliveryRetries)
+	retrierCtx, stopRetrier := context.WithCancel(context.Background())
+	defer stopRetrier()
+	go notificationRetrier.Run(retrierCtx, cfg.DeliveryRetryInterval, cfg.DeliveryRetryBatchSize)
+
+	twoPhaseActivities := activities.NewTwoPhaseActivities(db)
+	w.RegisterActivity(twoPhaseActivities.PrepareReservation)
+	w.RegisterActivity(twoPhaseActivities.Commit)
+	w.RegisterActivity(twoPhaseActivities.Abort)
 
 	// Start worker
 	err = w.Start()
 	if err != nil {
-		log.Fatalf("Failed to start worker: %v", err)
+		logger.Error("failed to start worker", err, nil)
+		os.Exit(1)
 	}
 
-	log.Println("Worker started successfully")
+	ready.Store(true)
+	logger.Info("worker started successfully", map[string]interface{}{
+		"workflows": registeredWorkflows, "activities": registeredActivities,
+	})
 
-	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	// Block until SIGINT/SIGTERM, then stop accepting new readiness checks
+	// before draining so a load balancer/orchestrator can pull this worker
+	// out of rotation ahead of the in-flight activities finishing.
+	<-worker.InterruptCh()
 
-	log.Println("Shutting down worker...")
+	ready.Store(false)
+	logger.Info("shutting down worker, draining in-flight activities", map[string]interface{}{
+		"grace_period": cfg.ShutdownGracePeriod.String(),
+	})
 	w.Stop()
-	log.Println("Worker stopped")
+	logger.Info("worker stopped", nil)
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	if err := healthServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("health server forced to shutdown", err, nil)
+	}
+}
+
+// registeredWorkflows and registeredActivities list everything this worker
+// registers, purely for the structured startup log line above -- keep them
+// in sync with the Register* calls in main.
+var registeredWorkflows = []string{
+	"BookingWorkflow", "PaymentValidationWorkflow", "WaitlistCoordinatorWorkflow",
+	"MultiFlightBookingWorkflow", "NotificationWorkflow",
+}
+
+var registeredActivities = []string{
+	"ReserveSeats", "ReleaseSeats", "UpdateSeats", "ConfirmSeats",
+	"AuthorizePayment", "GetTransferStatus", "CapturePayment", "RefundPayment", "UpdatePaymentRecord",
+	"UpdateOrderStatus", "CreateOrder", "RecordTransitionLog",
+	"SendEmailConfirmation", "SendSMSConfirmation", "SendWebhookConfirmation",
+	"PrepareReservation", "Commit", "Abort",
+}
+
+// dialTemporalClient dials Temporal in a goroutine so the call can be
+// bounded by ctx -- client.Dial itself has no context parameter, so a
+// stuck dependency would otherwise hang startup indefinitely.
+func dialTemporalClient(ctx context.Context, cfg *config.Config) (client.Client, error) {
+	type dialResult struct {
+		client client.Client
+		err    error
+	}
+	resultCh := make(chan dialResult, 1)
+	go func() {
+		c, err := client.Dial(client.Options{
+			HostPort:           cfg.TemporalAddress,
+			ContextPropagators: []workflow.ContextPropagator{correlation.NewPropagator()},
+		})
+		resultCh <- dialResult{c, err}
+	}()
+
+	select {
+	case res := <-resultCh:
+		return res.client, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// newHealthServer builds the /healthz (process alive) and /readyz (200
+// while ready is true, 503 during startup and while draining) endpoints
+// the worker exposes for operational health checks.
+func newHealthServer(port string, ready *atomic.Bool) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	return &http.Server{Addr: ":" + port, Handler: mux}
+}
+
+// newPaymentProvider builds the payments.Provider named by the
+// PAYMENT_PROVIDER config value.
+func newPaymentProvider(name string) (payments.Provider, error) {
+	switch name {
+	case "stripe":
+		return payments.NewStripeProvider(), nil
+	case "banktransfer":
+		return payments.NewBankTransferProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", name)
+	}
 }
@@ -3,29 +3,36 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"flight-booking-system/internal/config"
 	"flight-booking-system/internal/database"
+	"flight-booking-system/internal/logging"
 	"flight-booking-system/internal/models"
+	"flight-booking-system/internal/temporal/correlation"
 	"flight-booking-system/internal/temporal/workflows"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"go.temporal.io/api/serviceerror"
 	"go.temporal.io/sdk/client"
 )
 
 type Handler struct {
 	DB             *database.DB
 	TemporalClient client.Client
+	SSEHub         *SSEHub
 }
 
-func NewHandler(db *database.DB, temporalClient client.Client) *Handler {
+func NewHandler(db *database.DB, temporalClient client.Client, sseHub *SSEHub) *Handler {
 	return &Handler{
 		DB:             db,
 		TemporalClient: temporalClient,
+		SSEHub:         sseHub,
 	}
 }
 
@@ -39,6 +46,7 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	flightID := vars["flightId"]
+	requestID := logging.RequestIDFromContext(r.Context())
 
 	var req models.CreateOrderRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -53,23 +61,32 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	orderID := uuid.New().String()
+	logger := logging.FromContext(r.Context()).Session("CreateOrder",
+		map[string]interface{}{"order_id": orderID, "flight_id": flightID, "user_id": req.UserID})
 
-	// Start Temporal workflow
+	// Start Temporal workflow. The request ID rides along on the Memo (for
+	// operator visibility/search) and on the workflow context itself, via
+	// correlation.WithRequestID, so correlation.NewPropagator forwards it
+	// into BookingWorkflow and every activity it calls.
 	workflowOptions := client.StartWorkflowOptions{
 		ID:        orderID,
 		TaskQueue: "booking-task-queue",
+		Memo:      map[string]interface{}{"request_id": requestID},
 	}
 
 	input := models.BookingInput{
-		OrderID:  orderID,
-		FlightID: flightID,
-		UserID:   req.UserID,
-		Seats:    req.Seats,
+		OrderID:   orderID,
+		FlightID:  flightID,
+		UserID:    req.UserID,
+		Seats:     req.Seats,
+		RequestID: requestID,
 	}
 
-	we, err := h.TemporalClient.ExecuteWorkflow(context.Background(), workflowOptions,
+	ctx := correlation.WithRequestID(r.Context(), requestID)
+	we, err := h.TemporalClient.ExecuteWorkflow(ctx, workflowOptions,
 		workflows.BookingWorkflow, input)
 	if err != nil {
+		logger.Error("failed to start booking workflow", err, nil)
 		http.Error(w, fmt.Sprintf("failed to start workflow: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -85,10 +102,13 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 	//yuvald TODO why not orm?
 	if err := h.DB.CreateOrder(order); err != nil {
+		logger.Error("failed to create order record", err, nil)
 		http.Error(w, fmt.Sprintf("failed to create order: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	logger.Info("booking workflow started", map[string]interface{}{"workflow_id": we.GetID()})
+
 	// Wait a moment for workflow to process seat reservation
 	time.Sleep(100 * time.Millisecond)
 
@@ -103,6 +123,67 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// CreateMultiOrder books an itinerary spanning one or more flight legs
+// atomically via MultiFlightBookingWorkflow's two-phase commit, for the
+// connecting-itinerary case a single-flight CreateOrder can't express.
+func (h *Handler) CreateMultiOrder(w http.ResponseWriter, r *http.Request) {
+	requestID := logging.RequestIDFromContext(r.Context())
+
+	var req models.CreateMultiOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.UserID == "" || len(req.Legs) == 0 {
+		http.Error(w, "userId and at least one leg required", http.StatusBadRequest)
+		return
+	}
+	for _, leg := range req.Legs {
+		if leg.FlightID == "" || len(leg.Seats) == 0 {
+			http.Error(w, "each leg requires a flightId and at least one seat", http.StatusBadRequest)
+			return
+		}
+	}
+
+	itineraryID := uuid.New().String()
+	logger := logging.FromContext(r.Context()).Session("CreateMultiOrder",
+		map[string]interface{}{"itinerary_id": itineraryID, "user_id": req.UserID, "legs": len(req.Legs)})
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        itineraryID,
+		TaskQueue: "booking-task-queue",
+		Memo:      map[string]interface{}{"request_id": requestID},
+	}
+
+	input := models.MultiFlightBookingInput{
+		ItineraryID: itineraryID,
+		UserID:      req.UserID,
+		Legs:        req.Legs,
+		RequestID:   requestID,
+	}
+
+	ctx := correlation.WithRequestID(r.Context(), requestID)
+	we, err := h.TemporalClient.ExecuteWorkflow(ctx, workflowOptions,
+		workflows.MultiFlightBookingWorkflow, input)
+	if err != nil {
+		logger.Error("failed to start multi-flight booking workflow", err, nil)
+		http.Error(w, fmt.Sprintf("failed to start workflow: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("multi-flight booking workflow started", map[string]interface{}{"workflow_id": we.GetID()})
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.CreateMultiOrderResponse{
+		ItineraryID: itineraryID,
+		UserID:      req.UserID,
+		Legs:        req.Legs,
+		Status:      models.StatusCreated,
+		WorkflowID:  we.GetID(),
+	})
+}
+
 // GetOrderStatus retrieves the status of an order
 func (h *Handler) GetOrderStatus(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -115,6 +196,15 @@ func (h *Handler) GetOrderStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The transition log is only persisted once BookingWorkflow reaches a
+	// terminal state or fails, so it's nil/empty while a booking is still
+	// in progress -- that's expected, not an error.
+	transitionLog, err := h.DB.GetTransitionLog(r.Context(), orderID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to load transition log: %v", err), http.StatusInternalServerError)
+		return
+	}
+
 	// Query workflow for current state
 	resp, err := h.TemporalClient.QueryWorkflow(context.Background(), order.WorkflowID, order.RunID, workflows.QueryGetStatus)
 	if err != nil {
@@ -127,6 +217,7 @@ func (h *Handler) GetOrderStatus(w http.ResponseWriter, r *http.Request) {
 			Seats:         seats,
 			Status:        order.Status,
 			TimeRemaining: 0,
+			TransitionLog: transitionLog,
 		}
 		json.NewEncoder(w).Encode(response)
 		return
@@ -156,6 +247,7 @@ func (h *Handler) GetOrderStatus(w http.ResponseWriter, r *http.Request) {
 		Status:        state.Status,
 		TimeRemaining: timeRemaining,
 		ReservedAt:    &state.ReservationStartAt,
+		TransitionLog: transitionLog,
 	}
 
 	json.NewEncoder(w).Encode(response)
@@ -189,6 +281,8 @@ func (h *Handler) UpdateSeats(w http.ResponseWriter, r *http.Request) {
 	err = h.TemporalClient.SignalWorkflow(context.Background(), order.WorkflowID, order.RunID,
 		workflows.SignalUpdateSeats, req.Seats)
 	if err != nil {
+		logging.FromContext(r.Context()).Session("UpdateSeats", map[string]interface{}{"order_id": orderID}).
+			Error("failed to signal seat update", err, nil)
 		http.Error(w, fmt.Sprintf("failed to send signal: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -201,6 +295,9 @@ func (h *Handler) UpdateSeats(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) SubmitPayment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	orderID := vars["orderId"]
+	requestID := logging.RequestIDFromContext(r.Context())
+	logger := logging.FromContext(r.Context()).Session("SubmitPayment",
+		map[string]interface{}{"order_id": orderID})
 
 	var req models.SubmitPaymentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -221,10 +318,21 @@ func (h *Handler) SubmitPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Send signal to workflow
+	// Send signal to workflow. The Idempotency-Key, if present, rides along
+	// so the payment child workflow's ID can incorporate it (see
+	// bookingMachine.runPaymentValidation) and a retried SubmitPayment call
+	// can't trigger a second charge. The request ID rides along too, so
+	// AuthorizePayment's logs correlate to this specific SubmitPayment call
+	// rather than the original CreateOrder request.
+	signal := models.SubmitPaymentSignal{
+		PaymentCode:    req.PaymentCode,
+		IdempotencyKey: r.Header.Get("Idempotency-Key"),
+		RequestID:      requestID,
+	}
 	err = h.TemporalClient.SignalWorkflow(context.Background(), order.WorkflowID, order.RunID,
-		workflows.SignalSubmitPayment, req.PaymentCode)
+		workflows.SignalSubmitPayment, signal)
 	if err != nil {
+		logger.Error("failed to signal payment submission", err, nil)
 		http.Error(w, fmt.Sprintf("failed to send signal: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -249,6 +357,8 @@ func (h *Handler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 	err = h.TemporalClient.SignalWorkflow(context.Background(), order.WorkflowID, order.RunID,
 		workflows.SignalCancelOrder, true)
 	if err != nil {
+		logging.FromContext(r.Context()).Session("CancelOrder", map[string]interface{}{"order_id": orderID}).
+			Error("failed to signal order cancellation", err, nil)
 		http.Error(w, fmt.Sprintf("failed to send signal: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -276,6 +386,191 @@ func (h *Handler) GetSeats(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// StreamSeatEvents streams a flight's seat status transitions as
+// Server-Sent Events, so a frontend can live-update its seat map instead
+// of polling GetSeats. A client reconnecting with a Last-Event-ID header is
+// subscribed to the live feed first and then backfilled from the durable
+// seat_events outbox, so nothing published in between is missed; the live
+// loop dedupes against the backfill by sequence number.
+func (h *Handler) StreamSeatEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	vars := mux.Vars(r)
+	flightID := vars["flightId"]
+
+	var lastSeq int64
+	if raw := r.Header.Get("Last-Event-ID"); raw != "" {
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			lastSeq = n
+		}
+	}
+
+	// Subscribe before backfilling, not after: otherwise an event published
+	// between the SeatEventsSince query and the subscribe call would never
+	// reach this client -- missed by the backfill's snapshot and by the
+	// live feed, since nothing was listening yet. Subscribing first instead
+	// risks the opposite, harmless overlap (an event lands in ch that the
+	// backfill query also picks up), which the live loop already dedupes
+	// against lastSeq below.
+	ch := h.SSEHub.subscribe(flightID)
+	defer h.SSEHub.unsubscribe(flightID, ch)
+
+	backfill, err := h.DB.SeatEventsSince(r.Context(), flightID, lastSeq)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to load seat events: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, e := range backfill {
+		writeSeatEvent(w, e)
+		lastSeq = e.Seq
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-ch:
+			if e.Seq <= lastSeq {
+				continue // already sent during backfill
+			}
+			writeSeatEvent(w, e)
+			lastSeq = e.Seq
+			flusher.Flush()
+		}
+	}
+}
+
+func writeSeatEvent(w http.ResponseWriter, e models.SeatEvent) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.Seq, body)
+}
+
+// JoinWaitlist queues a user for seats on a (likely sold-out) flight. It
+// signal-with-starts the flight's WaitlistCoordinatorWorkflow so the first
+// joiner spins the coordinator up and later joiners just signal it.
+func (h *Handler) JoinWaitlist(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	flightID := vars["flightId"]
+
+	var req models.JoinWaitlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.UserID == "" || req.SeatsRequested <= 0 {
+		http.Error(w, "userId and a positive seatsRequested required", http.StatusBadRequest)
+		return
+	}
+
+	entry := models.WaitlistEntry{
+		EntryID:        uuid.New().String(),
+		FlightID:       flightID,
+		UserID:         req.UserID,
+		SeatsRequested: req.SeatsRequested,
+		PreferredSeats: req.PreferredSeats,
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        workflows.WaitlistCoordinatorID(flightID),
+		TaskQueue: "booking-task-queue",
+	}
+
+	_, err := h.TemporalClient.SignalWithStartWorkflow(context.Background(),
+		workflowOptions.ID, workflows.SignalWaitlistJoin, entry, workflowOptions,
+		workflows.WaitlistCoordinatorWorkflow, flightID, nil, nil)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to join waitlist: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.JoinWaitlistResponse{
+		EntryID:  entry.EntryID,
+		FlightID: flightID,
+	})
+}
+
+// GetWaitlistStatus reports an entry's position and estimated wait.
+func (h *Handler) GetWaitlistStatus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	flightID := vars["flightId"]
+	entryID := vars["entryId"]
+
+	resp, err := h.TemporalClient.QueryWorkflow(context.Background(), workflows.WaitlistCoordinatorID(flightID), "",
+		workflows.QueryWaitlistStatus, entryID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("waitlist not found: %v", err), http.StatusNotFound)
+		return
+	}
+
+	var status models.WaitlistStatusResponse
+	if err := resp.Get(&status); err != nil {
+		http.Error(w, fmt.Sprintf("failed to get waitlist status: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(status)
+}
+
+// LeaveWaitlist removes an entry from a flight's waitlist queue. Signaling
+// a coordinator that isn't running (the flight never went to waitlist)
+// returns NotFound, which this treats as success, and signaling a running
+// one whose queue no longer holds the entry (it already left or was
+// promoted) is a no-op, so this doesn't fail just because the entry is
+// already gone.
+func (h *Handler) LeaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	flightID := vars["flightId"]
+	entryID := vars["entryId"]
+
+	err := h.TemporalClient.SignalWorkflow(context.Background(), workflows.WaitlistCoordinatorID(flightID), "",
+		workflows.SignalWaitlistLeave, entryID)
+	var notFound *serviceerror.NotFound
+	if err != nil && !errors.As(err, &notFound) {
+		http.Error(w, fmt.Sprintf("failed to leave waitlist: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWaitlistQueue is an admin endpoint to inspect the full queue for a
+// flight.
+func (h *Handler) GetWaitlistQueue(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	flightID := vars["flightId"]
+
+	resp, err := h.TemporalClient.QueryWorkflow(context.Background(), workflows.WaitlistCoordinatorID(flightID), "",
+		workflows.QueryWaitlistQueue)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("waitlist not found: %v", err), http.StatusNotFound)
+		return
+	}
+
+	var queue []models.WaitlistEntry
+	if err := resp.Get(&queue); err != nil {
+		http.Error(w, fmt.Sprintf("failed to get waitlist queue: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(queue)
+}
+
 // ResetFlight resets all seats for a flight (admin/testing)
 func (h *Handler) ResetFlight(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -296,3 +591,31 @@ func (h *Handler) ResetFlight(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "flight reset"})
 }
+
+// SweepExpiredReservations runs one pass of database.ReservationSweeper's
+// work on demand (admin/testing), rather than waiting for its next timer
+// tick, and reports how many orders it expired.
+func (h *Handler) SweepExpiredReservations(w http.ResponseWriter, r *http.Request) {
+	batchSize := 100
+	if raw := r.URL.Query().Get("batchSize"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			batchSize = n
+		}
+	}
+
+	expired, err := h.DB.CleanupExpiredReservations(r.Context(), batchSize)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to sweep expired reservations: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	for _, exp := range expired {
+		if exp.WorkflowID != "" {
+			_ = h.TemporalClient.TerminateWorkflow(r.Context(), exp.WorkflowID, exp.RunID, "reservation expired")
+		}
+		_ = h.TemporalClient.SignalWorkflow(r.Context(), workflows.WaitlistCoordinatorID(exp.FlightID), "",
+			workflows.SignalSeatsFreed, workflows.SeatsFreedSignal{Seats: exp.Seats})
+	}
+
+	json.NewEncoder(w).Encode(map[string]int{"expiredCount": len(expired)})
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"flight-booking-system/internal/config"
+	"flight-booking-system/internal/database"
+	"flight-booking-system/internal/logging"
+	"flight-booking-system/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// rootLogger is the api package's root structured logger. LoggingMiddleware
+// Sessions a request-scoped child off of it and stashes that child on the
+// request context for handlers to recover via logging.FromContext.
+var rootLogger = logging.New("flight-booking-api")
+
+// IdempotencyMiddleware makes a mutating handler safe for a client to retry.
+// A request carrying an Idempotency-Key header is hashed and atomically
+// claimed by (key, route) via DB.ClaimIdempotencyKey: the request that wins
+// the claim runs next and persists its response for next time; a repeat
+// that loses the claim either replays the stored response (within TTL, same
+// hash), gets 409'd (different hash, or the original is still IN_PROGRESS),
+// or reclaims the key itself (the stored response has aged past TTL).
+// Requests without the header are passed through untouched.
+func IdempotencyMiddleware(db *database.DB) func(http.HandlerFunc) http.HandlerFunc {
+	ttl := config.Load().IdempotencyKeyTTL
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" {
+				next(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("failed to read request body: %v", err), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			route := idempotencyRoute(r)
+			hash := hashRequestBody(body)
+			userID := r.Header.Get("X-User-Id")
+
+			claimed, err := db.ClaimIdempotencyKey(r.Context(), &models.IdempotencyRecord{
+				Key: key, Route: route, UserID: userID, RequestHash: hash,
+			})
+			if err != nil {
+				http.Error(w, fmt.Sprintf("failed to claim idempotency key: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			if !claimed {
+				existing, err := db.GetIdempotencyRecord(r.Context(), key, route)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("failed to check idempotency key: %v", err), http.StatusInternalServerError)
+					return
+				}
+
+				switch {
+				case existing.Status == models.IdempotencyStatusInProgress:
+					http.Error(w, "a request with this Idempotency-Key is already in progress", http.StatusConflict)
+					return
+
+				case existing.RequestHash != hash:
+					http.Error(w, "Idempotency-Key already used with a different request body", http.StatusConflict)
+					return
+
+				case time.Since(existing.CreatedAt) < ttl:
+					w.WriteHeader(existing.ResponseStatus)
+					w.Write(existing.ResponseBody)
+					return
+
+				default:
+					// Same key and hash, but the stored response is past TTL.
+					// Try to reclaim it; if another request beat us to the
+					// reclaim, it owns this attempt now and ours must retry.
+					reclaimed, err := db.ReclaimExpiredIdempotencyKey(r.Context(), key, route, userID, hash, time.Now().Add(-ttl))
+					if err != nil {
+						http.Error(w, fmt.Sprintf("failed to reclaim idempotency key: %v", err), http.StatusInternalServerError)
+						return
+					}
+					if !reclaimed {
+						http.Error(w, "a request with this Idempotency-Key is already in progress", http.StatusConflict)
+						return
+					}
+				}
+			}
+
+			rec := newResponseRecorder()
+			next(rec, r)
+
+			for k, values := range rec.header {
+				w.Header()[k] = values
+			}
+			w.WriteHeader(rec.statusCode)
+			w.Write(rec.body.Bytes())
+
+			if err := db.CompleteIdempotencyRecord(r.Context(), key, route, rec.statusCode, rec.body.Bytes()); err != nil {
+				logging.FromContext(r.Context()).Error("failed to persist idempotency record", err,
+					map[string]interface{}{"idempotency_key": key, "route": route})
+			}
+		}
+	}
+}
+
+// idempotencyRoute identifies the (method, path template) pair a key was
+// used against, since the same path template can be registered under
+// different methods (e.g. GET and DELETE on /orders/{orderId}).
+func idempotencyRoute(r *http.Request) string {
+	tmpl, err := mux.CurrentRoute(r).GetPathTemplate()
+	if err != nil {
+		tmpl = r.URL.Path
+	}
+	return r.Method + " " + tmpl
+}
+
+func hashRequestBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// responseRecorder buffers a handler's response so IdempotencyMiddleware can
+// persist it before relaying it to the real client.
+type responseRecorder struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: make(http.Header), statusCode: http.StatusOK}
+}
+
+func (r *responseRecorder) Header() http.Header { return r.header }
+
+func (r *responseRecorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+
+func (r *responseRecorder) WriteHeader(status int) { r.statusCode = status }
+
+// CORSMiddleware allows the static frontend (served from a different
+// origin in local dev) to call the API.
+func CORSMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Idempotency-Key, X-Request-ID")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// JSONMiddleware marks every API response as JSON.
+func JSONMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LoggingMiddleware generates (or propagates, if the client already sent
+// one) an X-Request-ID, echoes it back on the response, and stashes a
+// request-scoped session logger carrying it on the request context for
+// handlers to recover via logging.FromContext. It also logs each request's
+// outcome once the handler chain returns.
+func LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(logging.RequestIDHeader)
+		if requestID == "" {
+			requestID = logging.NewRequestID()
+		}
+		w.Header().Set(logging.RequestIDHeader, requestID)
+
+		reqLogger := rootLogger.Session("http_request", map[string]interface{}{
+			"request_id": requestID,
+			"method":     r.Method,
+			"path":       r.URL.Path,
+		})
+
+		ctx := logging.NewContext(r.Context(), reqLogger)
+		ctx = logging.WithRequestID(ctx, requestID)
+		r = r.WithContext(ctx)
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		reqLogger.Info("handled http request", map[string]interface{}{
+			"status":      rec.statusCode,
+			"duration_ms": time.Since(start).Milliseconds(),
+		})
+	})
+}
+
+// statusRecorder passes writes straight through to the real
+// ResponseWriter, only intercepting WriteHeader to remember the status
+// code for the completion log line. It also forwards Flush, so streaming
+// handlers like StreamSeatEvents still see an http.Flusher behind
+// LoggingMiddleware.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.statusCode = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
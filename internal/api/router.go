@@ -17,21 +17,33 @@ func NewRouter(h *Handler) *mux.Router {
 	api := r.PathPrefix("/api").Subrouter()
 	api.Use(JSONMiddleware)
 
+	// The order, payment and admin mutating routes get idempotency-key
+	// replay/conflict handling on top of the JSON middleware above; the
+	// waitlist join/leave routes are not wrapped.
+	idempotent := IdempotencyMiddleware(h.DB)
+
 	// Health check
 	r.HandleFunc("/health", h.HealthCheck).Methods("GET")
 
 	// Flight routes
-	api.HandleFunc("/flights/{flightId}/orders", h.CreateOrder).Methods("POST")
+	api.HandleFunc("/flights/{flightId}/orders", idempotent(h.CreateOrder)).Methods("POST")
 	api.HandleFunc("/flights/{flightId}/seats", h.GetSeats).Methods("GET")
+	api.HandleFunc("/flights/{flightId}/events", h.StreamSeatEvents).Methods("GET")
+	api.HandleFunc("/flights/{flightId}/waitlist", h.JoinWaitlist).Methods("POST")
+	api.HandleFunc("/flights/{flightId}/waitlist/{entryId}", h.GetWaitlistStatus).Methods("GET")
+	api.HandleFunc("/flights/{flightId}/waitlist/{entryId}", h.LeaveWaitlist).Methods("DELETE")
 
 	// Order routes
+	api.HandleFunc("/orders/multi", idempotent(h.CreateMultiOrder)).Methods("POST")
 	api.HandleFunc("/orders/{orderId}", h.GetOrderStatus).Methods("GET")
-	api.HandleFunc("/orders/{orderId}/seats", h.UpdateSeats).Methods("POST")
-	api.HandleFunc("/orders/{orderId}/payment", h.SubmitPayment).Methods("POST")
-	api.HandleFunc("/orders/{orderId}", h.CancelOrder).Methods("DELETE")
+	api.HandleFunc("/orders/{orderId}/seats", idempotent(h.UpdateSeats)).Methods("POST")
+	api.HandleFunc("/orders/{orderId}/payment", idempotent(h.SubmitPayment)).Methods("POST")
+	api.HandleFunc("/orders/{orderId}", idempotent(h.CancelOrder)).Methods("DELETE")
 
 	// Admin routes (for testing)
-	api.HandleFunc("/admin/flights/{flightId}/reset", h.ResetFlight).Methods("POST")
+	api.HandleFunc("/admin/flights/{flightId}/reset", idempotent(h.ResetFlight)).Methods("POST")
+	api.HandleFunc("/admin/flights/{flightId}/waitlist", h.GetWaitlistQueue).Methods("GET")
+	api.HandleFunc("/admin/reservations/sweep", idempotent(h.SweepExpiredReservations)).Methods("POST")
 
 	// Serve static files
 	r.PathPrefix("/").Handler(http.FileServer(http.Dir("./static")))
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"sync"
+
+	"flight-booking-system/internal/models"
+)
+
+// sseSubscriberBuffer bounds how many unflushed events a single SSE
+// connection tolerates before SSEHub.Publish starts dropping them for
+// that subscriber -- a slow client shouldn't stall the relay or other
+// subscribers.
+const sseSubscriberBuffer = 16
+
+// SSEHub is a database.EventSink that fans seat events out to whatever
+// GET /flights/{flightId}/events connections are currently subscribed to
+// that flight. It holds no history itself -- a client resuming with
+// Last-Event-ID is subscribed here first and only then backfilled from
+// DB.SeatEventsSince, since the outbox is already the durable record (see
+// StreamSeatEvents for why subscribe has to come before backfill).
+type SSEHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan models.SeatEvent]struct{}
+}
+
+func NewSSEHub() *SSEHub {
+	return &SSEHub{subs: make(map[string]map[chan models.SeatEvent]struct{})}
+}
+
+// Publish implements database.EventSink.
+func (h *SSEHub) Publish(ctx context.Context, events []models.SeatEvent) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, e := range events {
+		for ch := range h.subs[e.FlightID] {
+			select {
+			case ch <- e:
+			default:
+				// Slow subscriber; drop rather than block the relay. It'll
+				// catch up on reconnect via Last-Event-ID.
+			}
+		}
+	}
+	return nil
+}
+
+func (h *SSEHub) subscribe(flightID string) chan models.SeatEvent {
+	ch := make(chan models.SeatEvent, sseSubscriberBuffer)
+
+	h.mu.Lock()
+	if h.subs[flightID] == nil {
+		h.subs[flightID] = make(map[chan models.SeatEvent]struct{})
+	}
+	h.subs[flightID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch
+}
+
+func (h *SSEHub) unsubscribe(flightID string, ch chan models.SeatEvent) {
+	h.mu.Lock()
+	delete(h.subs[flightID], ch)
+	if len(h.subs[flightID]) == 0 {
+		delete(h.subs, flightID)
+	}
+	h.mu.Unlock()
+}
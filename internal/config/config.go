@@ -7,22 +7,44 @@ import (
 )
 
 type Config struct {
-	ServerPort         string
-	DatabaseDSN        string
-	TemporalAddress    string
-	ReservationTimeout time.Duration
-	PaymentTimeout     time.Duration
-	MaxPaymentRetries  int
+	ServerPort                string
+	DatabaseDSN               string
+	TemporalAddress           string
+	ReservationTimeout        time.Duration
+	PaymentTimeout            time.Duration
+	MaxPaymentRetries         int
+	IdempotencyKeyTTL         time.Duration
+	ReservationSweepInterval  time.Duration
+	ReservationSweepBatchSize int
+	SeatEventRelayInterval    time.Duration
+	DeliveryRetryInterval     time.Duration
+	DeliveryRetryBatchSize    int
+	MaxDeliveryRetries        int
+	PaymentProvider           string
+	StartupTimeout            time.Duration
+	ShutdownGracePeriod       time.Duration
+	WorkerHealthPort          string
 }
 
 func Load() *Config {
 	return &Config{
-		ServerPort:         getEnv("SERVER_PORT", "8080"),
-		DatabaseDSN:        getEnv("DATABASE_DSN", "booking_user:booking_pass@tcp(localhost:3306)/flight_booking?parseTime=true"),
-		TemporalAddress:    getEnv("TEMPORAL_ADDRESS", "localhost:7233"),
-		ReservationTimeout: parseDuration(getEnv("RESERVATION_TIMEOUT", "15m")),
-		PaymentTimeout:     parseDuration(getEnv("PAYMENT_TIMEOUT", "10s")),
-		MaxPaymentRetries:  parseInt(getEnv("MAX_PAYMENT_RETRIES", "3")),
+		ServerPort:                getEnv("SERVER_PORT", "8080"),
+		DatabaseDSN:               getEnv("DATABASE_DSN", "booking_user:booking_pass@tcp(localhost:3306)/flight_booking?parseTime=true"),
+		TemporalAddress:           getEnv("TEMPORAL_ADDRESS", "localhost:7233"),
+		ReservationTimeout:        parseDuration(getEnv("RESERVATION_TIMEOUT", "15m")),
+		PaymentTimeout:            parseDuration(getEnv("PAYMENT_TIMEOUT", "10s")),
+		MaxPaymentRetries:         parseInt(getEnv("MAX_PAYMENT_RETRIES", "3")),
+		IdempotencyKeyTTL:         parseDuration(getEnv("IDEMPOTENCY_KEY_TTL", "24h")),
+		ReservationSweepInterval:  parseDuration(getEnv("RESERVATION_SWEEP_INTERVAL", "1m")),
+		ReservationSweepBatchSize: parseInt(getEnv("RESERVATION_SWEEP_BATCH_SIZE", "100")),
+		SeatEventRelayInterval:    parseDuration(getEnv("SEAT_EVENT_RELAY_INTERVAL", "500ms")),
+		DeliveryRetryInterval:     parseDuration(getEnv("DELIVERY_RETRY_INTERVAL", "1m")),
+		DeliveryRetryBatchSize:    parseInt(getEnv("DELIVERY_RETRY_BATCH_SIZE", "50")),
+		MaxDeliveryRetries:        parseInt(getEnv("MAX_DELIVERY_RETRIES", "3")),
+		PaymentProvider:           getEnv("PAYMENT_PROVIDER", "stripe"),
+		StartupTimeout:            parseDuration(getEnv("STARTUP_TIMEOUT", "10s")),
+		ShutdownGracePeriod:       parseDuration(getEnv("SHUTDOWN_GRACE_PERIOD", "30s")),
+		WorkerHealthPort:          getEnv("WORKER_HEALTH_PORT", "8090"),
 	}
 }
 
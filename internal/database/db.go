@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -11,16 +12,29 @@ import (
 
 // Sentinel errors for non-retriable conditions
 var (
-	ErrSeatNotAvailable = errors.New("seat not available")
-	ErrSeatNotExist     = errors.New("seat does not exist")
-	ErrOrderNotFound    = errors.New("order not found")
+	ErrSeatNotAvailable          = errors.New("seat not available")
+	ErrSeatNotExist              = errors.New("seat does not exist")
+	ErrOrderNotFound             = errors.New("order not found")
+	ErrIdempotencyRecordNotFound = errors.New("idempotency record not found")
 )
 
 type DB struct {
 	*sql.DB
+
+	// ReservationTimeout is how long a RESERVED seat is held before it's
+	// considered stale and eligible to be taken over or swept (a 2PC
+	// PENDING hold does not expire on it; only Commit/Abort releases it),
+	// config.Config.ReservationTimeout threaded through at
+	// construction so every call site that needs it -- reserveSeatsTxn,
+	// updateSeatsTxn, prepareLegTxn, CleanupExpiredReservations -- agrees
+	// with the window the booking workflow's own reservation timer uses.
+	ReservationTimeout time.Duration
 }
 
-func NewDB(dsn string) (*DB, error) {
+// NewDB opens the connection pool and verifies connectivity with a
+// PingContext bound to ctx, so a dependency that's down doesn't hang
+// startup forever -- callers typically pass a context.WithTimeout.
+func NewDB(ctx context.Context, dsn string, reservationTimeout time.Duration) (*DB, error) {
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -32,11 +46,11 @@ func NewDB(dsn string) (*DB, error) {
 	db.SetConnMaxLifetime(5 * time.Minute)
 
 	// Test connection
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &DB{db}, nil
+	return &DB{DB: db, ReservationTimeout: reservationTimeout}, nil
 }
 
 func (db *DB) Close() error {
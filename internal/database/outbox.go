@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"flight-booking-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// undispatchedSeatEventsBatchSize bounds how many outbox rows
+// SeatEventRelay reads per poll.
+const undispatchedSeatEventsBatchSize = 200
+
+// insertSeatEventTxn appends one row to the seat_events outbox within tx,
+// so it commits atomically with the seats-table change it describes.
+// dispatched starts FALSE; SeatEventRelay is what flips it once the event
+// has been published and acked.
+func insertSeatEventTxn(tx *sql.Tx, flightID, seatNumber, oldStatus, newStatus, orderID string) error {
+	_, err := tx.Exec(`
+		INSERT INTO seat_events (event_id, flight_id, seat_number, old_status, new_status, order_id, dispatched)
+		VALUES (?, ?, ?, ?, ?, ?, FALSE)
+	`, uuid.New().String(), flightID, seatNumber, oldStatus, newStatus, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to append seat event: %w", err)
+	}
+	return nil
+}
+
+// PollUndispatchedSeatEvents returns up to undispatchedSeatEventsBatchSize
+// not-yet-dispatched seat_events rows, oldest (lowest seq) first. Rows
+// aren't locked: if two relays poll concurrently they may both read (and
+// publish) the same rows, which is fine under the outbox's at-least-once
+// contract -- consumers dedupe on seq/event_id.
+func (db *DB) PollUndispatchedSeatEvents(ctx context.Context) ([]models.SeatEvent, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT event_id, seq, flight_id, seat_number, old_status, new_status, order_id, occurred_at
+		FROM seat_events
+		WHERE dispatched = FALSE
+		ORDER BY seq
+		LIMIT ?
+	`, undispatchedSeatEventsBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to poll seat events: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSeatEvents(rows)
+}
+
+// MarkSeatEventsDispatched flips dispatched to TRUE for the given event
+// IDs, once SeatEventRelay's sink has acked them.
+func (db *DB) MarkSeatEventsDispatched(ctx context.Context, eventIDs []string) error {
+	if len(eventIDs) == 0 {
+		return nil
+	}
+
+	placeholders := strings.Repeat("?,", len(eventIDs))
+	placeholders = placeholders[:len(placeholders)-1]
+
+	args := make([]interface{}, len(eventIDs))
+	for i, id := range eventIDs {
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`UPDATE seat_events SET dispatched = TRUE WHERE event_id IN (%s)`, placeholders)
+	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to mark seat events dispatched: %w", err)
+	}
+	return nil
+}
+
+// SeatEventsSince returns flightID's seat_events with seq greater than
+// afterSeq, oldest first -- the backfill an SSE client resuming via
+// Last-Event-ID needs before it starts tailing live events.
+func (db *DB) SeatEventsSince(ctx context.Context, flightID string, afterSeq int64) ([]models.SeatEvent, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT event_id, seq, flight_id, seat_number, old_status, new_status, order_id, occurred_at
+		FROM seat_events
+		WHERE flight_id = ? AND seq > ?
+		ORDER BY seq
+	`, flightID, afterSeq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seat events: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSeatEvents(rows)
+}
+
+func scanSeatEvents(rows *sql.Rows) ([]models.SeatEvent, error) {
+	var events []models.SeatEvent
+	for rows.Next() {
+		var e models.SeatEvent
+		err := rows.Scan(&e.EventID, &e.Seq, &e.FlightID, &e.SeatNumber, &e.OldStatus, &e.NewStatus, &e.OrderID, &e.OccurredAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan seat event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// EventSink is SeatEventRelay's pluggable publish target: an in-process SSE
+// hub by default, or e.g. a Kafka/NATS publisher behind the same interface.
+// Publish must tolerate being called more than once for the same events --
+// the relay only guarantees at-least-once delivery.
+type EventSink interface {
+	Publish(ctx context.Context, events []models.SeatEvent) error
+}
+
+// SeatEventRelay tails the seat_events outbox on an interval and publishes
+// newly-appended, not-yet-dispatched batches to a sink, marking them
+// dispatched only once the sink acks them. If the process dies between a
+// successful Publish and the dispatched flag being written, the same
+// events get republished on restart -- at-least-once, not exactly-once, by
+// design.
+type SeatEventRelay struct {
+	db       *DB
+	sink     EventSink
+	interval time.Duration
+}
+
+// NewSeatEventRelay builds a relay that polls for undispatched seat events
+// every interval and hands them to sink.
+func NewSeatEventRelay(db *DB, sink EventSink, interval time.Duration) *SeatEventRelay {
+	return &SeatEventRelay{db: db, sink: sink, interval: interval}
+}
+
+// Run polls on r.interval until ctx is cancelled. Meant to be started in
+// its own goroutine from main.
+func (r *SeatEventRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.relayOnce(ctx)
+		}
+	}
+}
+
+func (r *SeatEventRelay) relayOnce(ctx context.Context) {
+	events, err := r.db.PollUndispatchedSeatEvents(ctx)
+	if err != nil || len(events) == 0 {
+		return
+	}
+
+	if err := r.sink.Publish(ctx, events); err != nil {
+		return
+	}
+
+	ids := make([]string, len(events))
+	for i, e := range events {
+		ids[i] = e.EventID
+	}
+	r.db.MarkSeatEventsDispatched(ctx, ids)
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -10,20 +11,23 @@ import (
 	"flight-booking-system/internal/models"
 )
 
-// ReserveSeats reserves seats for an order with row-level locking
-func (db *DB) ReserveSeats(flightID string, seats []string, orderID, userID string) error {
-	tx, err := db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
+// ReserveSeats reserves seats for an order with row-level locking. The
+// whole transaction runs through RunInTxn so lock contention from
+// concurrent bookings (deadlock 1213, lock-wait-timeout 1205) is retried
+// here instead of surfacing as a hard activity failure.
+func (db *DB) ReserveSeats(ctx context.Context, flightID string, seats []string, orderID, userID string) error {
+	return db.RunInTxn(ctx, true, func(tx *sql.Tx) error {
+		return reserveSeatsTxn(tx, flightID, seats, orderID, userID, db.ReservationTimeout)
+	})
+}
 
+func reserveSeatsTxn(tx *sql.Tx, flightID string, seats []string, orderID, userID string, reservationTimeout time.Duration) error {
 	// Lock rows for update
 	placeholders := strings.Repeat("?,", len(seats))
 	placeholders = placeholders[:len(placeholders)-1]
 
 	query := fmt.Sprintf(`
-		SELECT seat_id, seat_number, status, reserved_at
+		SELECT seat_id, seat_number, status, reserved_by, reserved_at
 		FROM seats
 		WHERE flight_id = ? AND seat_number IN (%s)
 		FOR UPDATE
@@ -41,23 +45,36 @@ func (db *DB) ReserveSeats(flightID string, seats []string, orderID, userID stri
 	}
 	defer rows.Close()
 
-	// Check availability (including expired reservations)
+	// Check availability (including expired reservations, and seats this
+	// same order already holds from a replayed activity invocation).
 	foundSeats := make(map[string]bool)
+	oldStatus := make(map[string]string, len(seats))
+	alreadyHeld := make(map[string]bool)
 	for rows.Next() {
 		var seatID, seatNumber, status string
+		var reservedBy sql.NullString
 		var reservedAt sql.NullTime
 
-		if err := rows.Scan(&seatID, &seatNumber, &status, &reservedAt); err != nil {
+		if err := rows.Scan(&seatID, &seatNumber, &status, &reservedBy, &reservedAt); err != nil {
 			return fmt.Errorf("failed to scan seat: %w", err)
 		}
 
 		foundSeats[seatNumber] = true
+		oldStatus[seatNumber] = status
 
 		// Check if seat is available or reservation expired
 		if status == models.SeatAvailable {
 			continue
-		} else if status == models.SeatReserved && reservedAt.Valid {
-			if time.Since(reservedAt.Time) > 15*time.Minute {
+		}
+		if status == models.SeatReserved && reservedBy.Valid && reservedBy.String == orderID {
+			// A Temporal activity retry/replay for this same order lands
+			// here after already reserving the seat the first time -- treat
+			// it as idempotently satisfied rather than a conflict.
+			alreadyHeld[seatNumber] = true
+			continue
+		}
+		if status == models.SeatReserved && reservedAt.Valid {
+			if time.Since(reservedAt.Time) > reservationTimeout {
 				continue // Expired reservation, can be taken
 			}
 		}
@@ -72,16 +89,29 @@ func (db *DB) ReserveSeats(flightID string, seats []string, orderID, userID stri
 		}
 	}
 
-	// Reserve the seats
+	toReserve := make([]string, 0, len(seats))
+	for _, seat := range seats {
+		if !alreadyHeld[seat] {
+			toReserve = append(toReserve, seat)
+		}
+	}
+	if len(toReserve) == 0 {
+		return nil
+	}
+
+	// Reserve the seats not already held by this order
+	reservePlaceholders := strings.Repeat("?,", len(toReserve))
+	reservePlaceholders = reservePlaceholders[:len(reservePlaceholders)-1]
+
 	updateQuery := fmt.Sprintf(`
 		UPDATE seats
 		SET status = ?, reserved_by = ?, user_id = ?, reserved_at = NOW()
 		WHERE flight_id = ? AND seat_number IN (%s)
-	`, placeholders)
+	`, reservePlaceholders)
 
-	updateArgs := make([]interface{}, 0, len(seats)+4)
+	updateArgs := make([]interface{}, 0, len(toReserve)+4)
 	updateArgs = append(updateArgs, models.SeatReserved, orderID, userID, flightID)
-	for _, seat := range seats {
+	for _, seat := range toReserve {
 		updateArgs = append(updateArgs, seat)
 	}
 
@@ -89,36 +119,196 @@ func (db *DB) ReserveSeats(flightID string, seats []string, orderID, userID stri
 		return fmt.Errorf("failed to reserve seats: %w", err)
 	}
 
-	return tx.Commit()
+	for _, seat := range toReserve {
+		if err := insertSeatEventTxn(tx, flightID, seat, oldStatus[seat], models.SeatReserved, orderID); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-// ReleaseSeats releases seats reserved by an order
-func (db *DB) ReleaseSeats(orderID string) error {
-	query := `
+// ReleaseSeats releases seats reserved by an order, retrying the whole
+// release through RunInTxn on transient lock contention, the same as
+// ReserveSeats.
+func (db *DB) ReleaseSeats(ctx context.Context, orderID string) error {
+	return db.RunInTxn(ctx, true, func(tx *sql.Tx) error {
+		return releaseSeatsTxn(tx, orderID)
+	})
+}
+
+func releaseSeatsTxn(tx *sql.Tx, orderID string) error {
+	rows, err := tx.Query(`SELECT flight_id, seat_number, status FROM seats WHERE reserved_by = ? FOR UPDATE`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to lock seats for release: %w", err)
+	}
+
+	type releasedSeat struct {
+		flightID, seatNumber, oldStatus string
+	}
+	var released []releasedSeat
+	for rows.Next() {
+		var s releasedSeat
+		if err := rows.Scan(&s.flightID, &s.seatNumber, &s.oldStatus); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan seat: %w", err)
+		}
+		released = append(released, s)
+	}
+	rows.Close()
+
+	if _, err := tx.Exec(`
 		UPDATE seats
 		SET status = ?, reserved_by = NULL, user_id = NULL, reserved_at = NULL
 		WHERE reserved_by = ?
-	`
-
-	_, err := db.Exec(query, models.SeatAvailable, orderID)
-	if err != nil {
+	`, models.SeatAvailable, orderID); err != nil {
 		return fmt.Errorf("failed to release seats: %w", err)
 	}
 
+	for _, s := range released {
+		if err := insertSeatEventTxn(tx, s.flightID, s.seatNumber, s.oldStatus, models.SeatAvailable, orderID); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// UpdateSeats updates seat selection for an order
-func (db *DB) UpdateSeats(orderID string, oldSeats, newSeats []string) error {
-	tx, err := db.Begin()
+// CleanupExpiredReservations releases up to batchSize orders' worth of
+// seats that have sat RESERVED past db.ReservationTimeout and marks each
+// such order EXPIRED, returning one ExpiredReservation per order it
+// touched so the caller can notify that order's workflow and flight
+// waitlist outside of this transaction. The candidate order IDs are
+// selected with `FOR UPDATE SKIP LOCKED`, so multiple replicas calling
+// this concurrently (or on a timer) just split the batch instead of
+// racing each other.
+func (db *DB) CleanupExpiredReservations(ctx context.Context, batchSize int) ([]models.ExpiredReservation, error) {
+	var expired []models.ExpiredReservation
+
+	err := db.RunInTxn(ctx, true, func(tx *sql.Tx) error {
+		query := fmt.Sprintf(`
+			SELECT DISTINCT reserved_by
+			FROM seats
+			WHERE status = ? AND reserved_at < NOW() - INTERVAL %d MINUTE
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		`, int(db.ReservationTimeout.Minutes()))
+		rows, err := tx.QueryContext(ctx, query, models.SeatReserved, batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to select expired reservations: %w", err)
+		}
+
+		var orderIDs []string
+		for rows.Next() {
+			var orderID sql.NullString
+			if err := rows.Scan(&orderID); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan expired reservation: %w", err)
+			}
+			if orderID.Valid {
+				orderIDs = append(orderIDs, orderID.String)
+			}
+		}
+		rows.Close()
+
+		for _, orderID := range orderIDs {
+			seats, err := expireOrderTxn(tx, orderID)
+			if err != nil {
+				return fmt.Errorf("order %s: %w", orderID, err)
+			}
+			if len(seats) == 0 {
+				continue
+			}
+
+			var flightID, workflowID, runID string
+			err = tx.QueryRow(`SELECT flight_id, workflow_id, run_id FROM orders WHERE order_id = ?`, orderID).
+				Scan(&flightID, &workflowID, &runID)
+			if errors.Is(err, sql.ErrNoRows) {
+				// Itinerary bookings (MultiFlightBookingWorkflow) never get an
+				// orders row -- reserved_by on their seats is the itinerary ID,
+				// so the seat release above still applies to them, there's just
+				// no single workflow/run ID here to notify. Skip reporting this
+				// one rather than failing the rest of the batch over it.
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("order %s: failed to load order: %w", orderID, err)
+			}
+
+			expired = append(expired, models.ExpiredReservation{
+				OrderID: orderID, FlightID: flightID, WorkflowID: workflowID, RunID: runID, Seats: seats,
+			})
+		}
+
+		return nil
+	})
+
+	return expired, err
+}
+
+// expireOrderTxn releases orderID's still-RESERVED seats and marks the
+// order EXPIRED, returning the seat numbers it released (nil if the order
+// had none left to release, e.g. a concurrent sweep already took it).
+func expireOrderTxn(tx *sql.Tx, orderID string) ([]string, error) {
+	rows, err := tx.Query(`SELECT flight_id, seat_number FROM seats WHERE reserved_by = ? AND status = ?`, orderID, models.SeatReserved)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to read reserved seats: %w", err)
+	}
+
+	type reservedSeat struct{ flightID, seatNumber string }
+	var reserved []reservedSeat
+	for rows.Next() {
+		var s reservedSeat
+		if err := rows.Scan(&s.flightID, &s.seatNumber); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan seat: %w", err)
+		}
+		reserved = append(reserved, s)
+	}
+	rows.Close()
+
+	if len(reserved) == 0 {
+		return nil, nil
+	}
+
+	seats := make([]string, len(reserved))
+	for i, s := range reserved {
+		seats[i] = s.seatNumber
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE seats
+		SET status = ?, reserved_by = NULL, user_id = NULL, reserved_at = NULL
+		WHERE reserved_by = ? AND status = ?
+	`, models.SeatAvailable, orderID, models.SeatReserved); err != nil {
+		return nil, fmt.Errorf("failed to release seats: %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE orders SET status = ?, updated_at = NOW() WHERE order_id = ?`, models.StatusExpired, orderID); err != nil {
+		return nil, fmt.Errorf("failed to mark order expired: %w", err)
 	}
-	defer tx.Rollback()
 
+	for _, s := range reserved {
+		if err := insertSeatEventTxn(tx, s.flightID, s.seatNumber, models.SeatReserved, models.SeatAvailable, orderID); err != nil {
+			return nil, err
+		}
+	}
+
+	return seats, nil
+}
+
+// UpdateSeats updates seat selection for an order, retrying the whole
+// swap through RunInTxn on transient lock contention.
+func (db *DB) UpdateSeats(ctx context.Context, orderID string, oldSeats, newSeats []string) error {
+	return db.RunInTxn(ctx, true, func(tx *sql.Tx) error {
+		return updateSeatsTxn(tx, orderID, oldSeats, newSeats, db.ReservationTimeout)
+	})
+}
+
+func updateSeatsTxn(tx *sql.Tx, orderID string, oldSeats, newSeats []string, reservationTimeout time.Duration) error {
 	// Get flight ID and user ID from the order
 	var flightID, userID string
-	err = tx.QueryRow("SELECT flight_id, user_id FROM orders WHERE order_id = ?", orderID).Scan(&flightID, &userID)
+	err := tx.QueryRow("SELECT flight_id, user_id FROM orders WHERE order_id = ?", orderID).Scan(&flightID, &userID)
 	if err != nil {
 		return fmt.Errorf("failed to get order info: %w", err)
 	}
@@ -143,6 +333,12 @@ func (db *DB) UpdateSeats(orderID string, oldSeats, newSeats []string) error {
 		if _, err := tx.Exec(releaseQuery, args...); err != nil {
 			return fmt.Errorf("failed to release old seats: %w", err)
 		}
+
+		for _, seat := range oldSeats {
+			if err := insertSeatEventTxn(tx, flightID, seat, models.SeatReserved, models.SeatAvailable, orderID); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Reserve new seats (with locking)
@@ -170,6 +366,7 @@ func (db *DB) UpdateSeats(orderID string, oldSeats, newSeats []string) error {
 		defer rows.Close()
 
 		foundSeats := make(map[string]bool)
+		oldStatus := make(map[string]string, len(newSeats))
 		for rows.Next() {
 			var seatID, seatNumber, status string
 			var reservedAt sql.NullTime
@@ -179,11 +376,12 @@ func (db *DB) UpdateSeats(orderID string, oldSeats, newSeats []string) error {
 			}
 
 			foundSeats[seatNumber] = true
+			oldStatus[seatNumber] = status
 
 			if status == models.SeatAvailable {
 				continue
 			} else if status == models.SeatReserved && reservedAt.Valid {
-				if time.Since(reservedAt.Time) > 15*time.Minute {
+				if time.Since(reservedAt.Time) > reservationTimeout {
 					continue
 				}
 			}
@@ -213,25 +411,49 @@ func (db *DB) UpdateSeats(orderID string, oldSeats, newSeats []string) error {
 		if _, err := tx.Exec(reserveQuery, reserveArgs...); err != nil {
 			return fmt.Errorf("failed to reserve new seats: %w", err)
 		}
+
+		for _, seat := range newSeats {
+			if err := insertSeatEventTxn(tx, flightID, seat, oldStatus[seat], models.SeatReserved, orderID); err != nil {
+				return err
+			}
+		}
 	}
 
-	return tx.Commit()
+	return nil
 }
 
 // ConfirmSeats confirms seats for an order (mark as BOOKED)
-func (db *DB) ConfirmSeats(orderID string) error {
-	query := `
-		UPDATE seats
-		SET status = ?
-		WHERE reserved_by = ?
-	`
+func (db *DB) ConfirmSeats(ctx context.Context, orderID string) error {
+	return db.RunInTxn(ctx, true, func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT flight_id, seat_number FROM seats WHERE reserved_by = ?`, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to read seats to confirm: %w", err)
+		}
 
-	_, err := db.Exec(query, models.SeatBooked, orderID)
-	if err != nil {
-		return fmt.Errorf("failed to confirm seats: %w", err)
-	}
+		type seat struct{ flightID, seatNumber string }
+		var confirmed []seat
+		for rows.Next() {
+			var s seat
+			if err := rows.Scan(&s.flightID, &s.seatNumber); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan seat: %w", err)
+			}
+			confirmed = append(confirmed, s)
+		}
+		rows.Close()
 
-	return nil
+		if _, err := tx.Exec(`UPDATE seats SET status = ? WHERE reserved_by = ?`, models.SeatBooked, orderID); err != nil {
+			return fmt.Errorf("failed to confirm seats: %w", err)
+		}
+
+		for _, s := range confirmed {
+			if err := insertSeatEventTxn(tx, s.flightID, s.seatNumber, models.SeatReserved, models.SeatBooked, orderID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 }
 
 // GetSeats retrieves all seats for a flight
@@ -340,6 +562,153 @@ func (db *DB) UpdateOrderStatus(orderID, status string) error {
 	return nil
 }
 
+// RecordTransitionLog persists a booking order's full FSM transition
+// history (fsm.FSM.Log) alongside the order, one row per transition, so
+// it's still auditable once the workflow has completed and aged out of
+// Temporal's retention. Called once, after the workflow reaches a
+// terminal state or fails, so there's no partial log to dedupe against
+// on activity replay -- a retried call just re-inserts the same rows,
+// which is harmless for an audit trail.
+func (db *DB) RecordTransitionLog(ctx context.Context, entries []models.TransitionLogEntry) error {
+	for _, e := range entries {
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO transition_logs (order_id, seq, from_state, to_state, event)
+			VALUES (?, ?, ?, ?, ?)
+		`, e.OrderID, e.Seq, e.FromState, e.ToState, e.Event)
+		if err != nil {
+			return fmt.Errorf("failed to record transition log entry %d: %w", e.Seq, err)
+		}
+	}
+	return nil
+}
+
+// GetTransitionLog returns orderID's persisted FSM transition history in
+// order, or nil if RecordTransitionLog hasn't run for it yet (e.g. its
+// workflow is still in progress).
+func (db *DB) GetTransitionLog(ctx context.Context, orderID string) ([]models.TransitionLogEntry, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT order_id, seq, from_state, to_state, event, created_at
+		FROM transition_logs
+		WHERE order_id = ?
+		ORDER BY seq
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transition log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.TransitionLogEntry
+	for rows.Next() {
+		var e models.TransitionLogEntry
+		if err := rows.Scan(&e.OrderID, &e.Seq, &e.FromState, &e.ToState, &e.Event, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transition log entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// UpdateOrCreatePayment updates the most recent payment record for an
+// order, or creates one if none exists yet. The update-then-maybe-insert
+// runs as a single RunInTxn closure so a retried activity invocation can't
+// race itself into two rows for the same attempt.
+func (db *DB) UpdateOrCreatePayment(ctx context.Context, payment *models.Payment) error {
+	return db.RunInTxn(ctx, true, func(tx *sql.Tx) error {
+		updateQuery := `
+			UPDATE payments
+			SET status = ?, provider = ?, transaction_id = ?, error_message = ?, updated_at = NOW()
+			WHERE order_id = ?
+			ORDER BY created_at DESC
+			LIMIT 1
+		`
+
+		result, err := tx.Exec(updateQuery, payment.Status, payment.Provider, payment.TransactionID, payment.ErrorMessage, payment.OrderID)
+		if err != nil {
+			return fmt.Errorf("failed to update payment record: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to check rows affected: %w", err)
+		}
+
+		if rowsAffected > 0 {
+			return nil
+		}
+
+		insertQuery := `
+			INSERT INTO payments (payment_id, order_id, payment_code, provider, transaction_id, status, error_message, attempts)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`
+		_, err = tx.Exec(insertQuery, payment.PaymentID, payment.OrderID, payment.PaymentCode, payment.Provider,
+			payment.TransactionID, payment.Status, payment.ErrorMessage, payment.Attempts)
+		if err != nil {
+			return fmt.Errorf("failed to create payment record: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// RecordDelivery persists one channel's notification attempt. Each call is
+// a fresh row -- unlike payments there's no "most recent attempt" to
+// update, since a retried channel send is its own delivery (RetryCount
+// distinguishes attempts for the same channel/order).
+func (db *DB) RecordDelivery(ctx context.Context, delivery *models.Delivery) error {
+	query := `
+		INSERT INTO deliveries (id, order_id, channel, status, retry_count, next_delivery_at, external_message_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := db.ExecContext(ctx, query, delivery.DeliveryID, delivery.OrderID, delivery.Channel,
+		delivery.Status, delivery.RetryCount, delivery.NextDeliveryAt, delivery.ExternalMessageID)
+	if err != nil {
+		return fmt.Errorf("failed to record delivery: %w", err)
+	}
+	return nil
+}
+
+// PollDeliveriesDueForRetry returns up to batchSize FAILED deliveries whose
+// NextDeliveryAt has arrived, oldest first, so NotificationRetrier can
+// requeue each through the same channel that first failed.
+func (db *DB) PollDeliveriesDueForRetry(ctx context.Context, batchSize int) ([]models.Delivery, error) {
+	query := `
+		SELECT id, order_id, channel, status, retry_count, next_delivery_at, external_message_id, created_at, updated_at
+		FROM deliveries
+		WHERE status = ? AND next_delivery_at IS NOT NULL AND next_delivery_at <= NOW()
+		ORDER BY next_delivery_at
+		LIMIT ?
+	`
+
+	rows, err := db.QueryContext(ctx, query, models.DeliveryStatusFailed, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to poll deliveries due for retry: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.Delivery
+	for rows.Next() {
+		var d models.Delivery
+		if err := rows.Scan(&d.DeliveryID, &d.OrderID, &d.Channel, &d.Status, &d.RetryCount,
+			&d.NextDeliveryAt, &d.ExternalMessageID, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// ClearDeliveryRetry clears a delivery's NextDeliveryAt once
+// NotificationRetrier has requeued it, so the same row isn't picked up
+// again by PollDeliveriesDueForRetry -- the requeued attempt gets its own
+// Delivery row via RecordDelivery.
+func (db *DB) ClearDeliveryRetry(ctx context.Context, deliveryID string) error {
+	_, err := db.ExecContext(ctx, `UPDATE deliveries SET next_delivery_at = NULL WHERE id = ?`, deliveryID)
+	if err != nil {
+		return fmt.Errorf("failed to clear delivery retry: %w", err)
+	}
+	return nil
+}
+
 // GetOrderSeats retrieves seats reserved for an order
 func (db *DB) GetOrderSeats(orderID string) ([]string, error) {
 	query := `
@@ -426,3 +795,112 @@ func (db *DB) DeleteOrdersByFlight(flightID string) error {
 
 	return nil
 }
+
+// GetIdempotencyRecord looks up the stored response for an Idempotency-Key
+// on a given route, returning ErrIdempotencyRecordNotFound if the key
+// hasn't been seen on that route before.
+func (db *DB) GetIdempotencyRecord(ctx context.Context, key, route string) (*models.IdempotencyRecord, error) {
+	query := `
+		SELECT idempotency_key, route, user_id, request_hash, status, response_status, response_body, created_at
+		FROM idempotency_keys
+		WHERE idempotency_key = ? AND route = ?
+	`
+
+	var rec models.IdempotencyRecord
+	err := db.QueryRowContext(ctx, query, key, route).Scan(
+		&rec.Key, &rec.Route, &rec.UserID, &rec.RequestHash, &rec.Status,
+		&rec.ResponseStatus, &rec.ResponseBody, &rec.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrIdempotencyRecordNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+
+	return &rec, nil
+}
+
+// ClaimIdempotencyKey atomically claims (key, route) for this request by
+// inserting an IN_PROGRESS row. The ON DUPLICATE KEY clause is a no-op
+// column-to-itself update, so MySQL's reported rows-affected count is the
+// deterministic signal of who won: 1 means this call inserted the row (the
+// claim succeeded), 0 means a row already existed -- a concurrent request
+// racing the same key, or a prior completed/in-progress one -- and the
+// caller must fall back to GetIdempotencyRecord to decide what to do.
+func (db *DB) ClaimIdempotencyKey(ctx context.Context, rec *models.IdempotencyRecord) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (idempotency_key, route, user_id, request_hash, status, response_status, response_body)
+		VALUES (?, ?, ?, ?, ?, 0, '')
+		ON DUPLICATE KEY UPDATE idempotency_key = idempotency_key
+	`
+
+	result, err := db.ExecContext(ctx, query, rec.Key, rec.Route, rec.UserID, rec.RequestHash, models.IdempotencyStatusInProgress)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// ReclaimExpiredIdempotencyKey takes over (key, route) from a COMPLETED row
+// that's aged out of the TTL, using the same rows-affected trick as
+// ClaimIdempotencyKey -- conditioned this time on the existing row still
+// looking expired at the moment of the UPDATE -- so two requests racing to
+// reclaim the same expired key can't both believe they won.
+func (db *DB) ReclaimExpiredIdempotencyKey(ctx context.Context, key, route, userID, hash string, olderThan time.Time) (bool, error) {
+	query := `
+		UPDATE idempotency_keys
+		SET user_id = ?, request_hash = ?, status = ?, response_status = 0, response_body = '', created_at = NOW()
+		WHERE idempotency_key = ? AND route = ? AND status = ? AND created_at < ?
+	`
+
+	result, err := db.ExecContext(ctx, query, userID, hash, models.IdempotencyStatusInProgress,
+		key, route, models.IdempotencyStatusCompleted, olderThan)
+	if err != nil {
+		return false, fmt.Errorf("failed to reclaim expired idempotency key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// CompleteIdempotencyRecord stamps the handler's response onto a (key,
+// route) this request claimed, moving it to COMPLETED so a replay within
+// TTL can return the response verbatim without re-running the handler.
+func (db *DB) CompleteIdempotencyRecord(ctx context.Context, key, route string, responseStatus int, responseBody []byte) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status = ?, response_status = ?, response_body = ?
+		WHERE idempotency_key = ? AND route = ?
+	`
+
+	_, err := db.ExecContext(ctx, query, models.IdempotencyStatusCompleted, responseStatus, responseBody, key, route)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeExpiredIdempotencyKeys deletes idempotency_keys rows older than ttl.
+// Called periodically by the sweeper goroutine started in cmd/server/main.go.
+func (db *DB) PurgeExpiredIdempotencyKeys(ctx context.Context, ttl time.Duration) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE created_at < ?`
+
+	result, err := db.ExecContext(ctx, query, time.Now().Add(-ttl))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired idempotency keys: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,320 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"flight-booking-system/internal/models"
+)
+
+// The tests below exercise reserveSeatsTxn's concurrency behavior against a
+// minimal fake database/sql/driver rather than a real MySQL connection, so
+// they can run without any external services. The fake gives each seat row
+// its own sync.Mutex, taken by a fake transaction's "FOR UPDATE" query and
+// released on Commit/Rollback -- the same exclusion a real FOR UPDATE would
+// give reserveSeatsTxn, just enforced in memory.
+
+type seatKey struct {
+	flightID, seatNumber string
+}
+
+type fakeSeatRow struct {
+	seatID     string
+	status     string
+	reservedBy string
+	reservedAt sql.NullTime
+}
+
+// fakeSeatStore is the in-memory backing store for one fake DSN, shared by
+// every fake connection opened against it.
+type fakeSeatStore struct {
+	mu    sync.Mutex
+	seats map[seatKey]*fakeSeatRow
+	locks map[seatKey]*sync.Mutex
+}
+
+func newFakeSeatStore() *fakeSeatStore {
+	return &fakeSeatStore{
+		seats: make(map[seatKey]*fakeSeatRow),
+		locks: make(map[seatKey]*sync.Mutex),
+	}
+}
+
+func (s *fakeSeatStore) seed(flightID string, seatNumbers []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, sn := range seatNumbers {
+		key := seatKey{flightID, sn}
+		s.seats[key] = &fakeSeatRow{seatID: fmt.Sprintf("%s-%d", flightID, i), status: models.SeatAvailable}
+		s.locks[key] = &sync.Mutex{}
+	}
+}
+
+func (s *fakeSeatStore) lockFor(key seatKey) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.locks[key]
+}
+
+var (
+	fakeSeatStoresMu sync.Mutex
+	fakeSeatStores   = map[string]*fakeSeatStore{}
+	fakeDriverOnce   sync.Once
+)
+
+func fakeStoreFor(dsn string) *fakeSeatStore {
+	fakeSeatStoresMu.Lock()
+	defer fakeSeatStoresMu.Unlock()
+	store, ok := fakeSeatStores[dsn]
+	if !ok {
+		store = newFakeSeatStore()
+		fakeSeatStores[dsn] = store
+	}
+	return store
+}
+
+func registerFakeSeatDriver() {
+	fakeDriverOnce.Do(func() {
+		sql.Register("fakeseatdriver", &fakeSeatDriver{})
+	})
+}
+
+type fakeSeatDriver struct{}
+
+func (d *fakeSeatDriver) Open(dsn string) (driver.Conn, error) {
+	return &fakeConn{store: fakeStoreFor(dsn)}, nil
+}
+
+// fakeConn holds at most one in-flight transaction, mirroring how
+// database/sql only ever runs one *sql.Tx at a time per pooled connection.
+type fakeConn struct {
+	store *fakeSeatStore
+	tx    *fakeTx
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fakeConn: Prepare not supported, use QueryerContext/ExecerContext")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	tx := &fakeTx{conn: c}
+	c.tx = tx
+	return tx, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.tx.query(query, args)
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.tx.exec(query, args)
+}
+
+// fakeTx locks one mutex per seat its SELECT ... FOR UPDATE touches, holding
+// them until Commit/Rollback -- real row-lock semantics for the one query
+// shape reserveSeatsTxn actually issues.
+type fakeTx struct {
+	conn  *fakeConn
+	locks []seatKey
+}
+
+func (tx *fakeTx) Commit() error   { return tx.release() }
+func (tx *fakeTx) Rollback() error { return tx.release() }
+
+func (tx *fakeTx) release() error {
+	store := tx.conn.store
+	for _, key := range tx.locks {
+		store.lockFor(key).Unlock()
+	}
+	tx.locks = nil
+	return nil
+}
+
+// query handles the one SELECT shape reserveSeatsTxn issues: lock every
+// requested seat row (blocking until held), then return its current state.
+func (tx *fakeTx) query(query string, args []driver.NamedValue) (driver.Rows, error) {
+	flightID, seatNumbers := flightAndSeatsFromArgs(args)
+
+	store := tx.conn.store
+	for _, sn := range seatNumbers {
+		key := seatKey{flightID, sn}
+		store.lockFor(key).Lock()
+		tx.locks = append(tx.locks, key)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	rows := &fakeRows{
+		cols: []string{"seat_id", "seat_number", "status", "reserved_by", "reserved_at"},
+	}
+	for _, sn := range seatNumbers {
+		row, ok := store.seats[seatKey{flightID, sn}]
+		if !ok {
+			continue
+		}
+		var reservedBy driver.Value
+		if row.reservedBy != "" {
+			reservedBy = row.reservedBy
+		}
+		var reservedAt driver.Value
+		if row.reservedAt.Valid {
+			reservedAt = row.reservedAt.Time
+		}
+		rows.data = append(rows.data, []driver.Value{row.seatID, sn, row.status, reservedBy, reservedAt})
+	}
+	return rows, nil
+}
+
+// exec routes to the one statement shape it cares about -- the seats
+// UPDATE -- and no-ops everything else (namely insertSeatEventTxn's outbox
+// INSERT, which this test has no need to observe).
+func (tx *fakeTx) exec(query string, args []driver.NamedValue) (driver.Result, error) {
+	if !strings.Contains(query, "UPDATE seats") {
+		return driver.RowsAffected(0), nil
+	}
+	return tx.execUpdateSeats(args)
+}
+
+// execUpdateSeats applies reserveSeatsTxn's UPDATE seats ... SET status =
+// ?, reserved_by = ?, user_id = ?, reserved_at = NOW() WHERE flight_id = ?
+// AND seat_number IN (...) to every seat in the args tail.
+func (tx *fakeTx) execUpdateSeats(args []driver.NamedValue) (driver.Result, error) {
+	if len(args) < 4 {
+		return driver.RowsAffected(0), nil
+	}
+	status := fmt.Sprint(args[0].Value)
+	reservedBy := fmt.Sprint(args[1].Value)
+	flightID := fmt.Sprint(args[3].Value)
+
+	store := tx.conn.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var affected int64
+	for _, a := range args[4:] {
+		sn := fmt.Sprint(a.Value)
+		row, ok := store.seats[seatKey{flightID, sn}]
+		if !ok {
+			continue
+		}
+		row.status = status
+		row.reservedBy = reservedBy
+		row.reservedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		affected++
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+// flightAndSeatsFromArgs pulls reserveSeatsTxn's "FOR UPDATE" args apart:
+// the flight ID first, then one seat number per remaining placeholder.
+func flightAndSeatsFromArgs(args []driver.NamedValue) (string, []string) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	flightID := fmt.Sprint(args[0].Value)
+	seats := make([]string, 0, len(args)-1)
+	for _, a := range args[1:] {
+		seats = append(seats, fmt.Sprint(a.Value))
+	}
+	return flightID, seats
+}
+
+type fakeRows struct {
+	cols []string
+	data [][]driver.Value
+	pos  int
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.data) {
+		return io.EOF
+	}
+	copy(dest, r.data[r.pos])
+	r.pos++
+	return nil
+}
+
+// TestReserveSeatsTxn_ConcurrentReservationsRespectCapacity runs many
+// concurrent ReserveSeats calls, each for a single distinct order, against a
+// flight with a fixed number of seats, and asserts that exactly capacity of
+// them succeed, the rest fail with ErrSeatNotAvailable, and no seat ends up
+// held by more than one order -- the guarantee reserveSeatsTxn's FOR UPDATE
+// locking exists to provide.
+func TestReserveSeatsTxn_ConcurrentReservationsRespectCapacity(t *testing.T) {
+	registerFakeSeatDriver()
+
+	dsn := t.Name()
+	sqlDB, err := sql.Open("fakeseatdriver", dsn)
+	if err != nil {
+		t.Fatalf("failed to open fake db: %v", err)
+	}
+	defer sqlDB.Close()
+
+	db := &DB{DB: sqlDB, ReservationTimeout: 15 * time.Minute}
+
+	const flightID = "FL-CONCURRENCY"
+	seats := []string{"1A", "2A", "3A"}
+	store := fakeStoreFor(dsn)
+	store.seed(flightID, seats)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			orderID := fmt.Sprintf("order-%d", i)
+			seat := seats[i%len(seats)]
+			results[i] = db.ReserveSeats(context.Background(), flightID, []string{seat}, orderID, "user-1")
+		}(i)
+	}
+	wg.Wait()
+
+	var succeeded, rejected int
+	for _, err := range results {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, ErrSeatNotAvailable):
+			rejected++
+		default:
+			t.Fatalf("unexpected error from ReserveSeats: %v", err)
+		}
+	}
+
+	if succeeded != len(seats) {
+		t.Fatalf("expected exactly %d successful reservations (flight capacity), got %d", len(seats), succeeded)
+	}
+	if rejected != callers-len(seats) {
+		t.Fatalf("expected %d rejections, got %d", callers-len(seats), rejected)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	claimedBy := make(map[string]bool)
+	for _, sn := range seats {
+		row := store.seats[seatKey{flightID, sn}]
+		if row.status != models.SeatReserved {
+			t.Fatalf("seat %s: expected status %s, got %s", sn, models.SeatReserved, row.status)
+		}
+		if claimedBy[row.reservedBy] {
+			t.Fatalf("seat %s: reserved_by %q already claimed another seat -- double booking", sn, row.reservedBy)
+		}
+		claimedBy[row.reservedBy] = true
+	}
+}
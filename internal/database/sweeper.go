@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"flight-booking-system/internal/models"
+)
+
+// Sweep metrics, exported via expvar (served at /debug/vars wherever this
+// process's default http.ServeMux is wired up) rather than a dedicated
+// metrics dependency, since this repo doesn't have one yet.
+var (
+	seatsExpiredTotal    = expvar.NewInt("seats_expired_total")
+	sweepDurationSeconds = expvar.NewFloat("sweep_duration_seconds")
+)
+
+// ReservationSweeper periodically calls DB.CleanupExpiredReservations so
+// expired RESERVED seats stop showing up in GetSeats (and any UI built on
+// it) even when the owning BookingWorkflow never gets a chance to run its
+// own reservation timer -- e.g. the workflow already terminated some other
+// way, or no worker is currently processing its task queue. It's safe to
+// run from every API replica: CleanupExpiredReservations' batch select uses
+// FOR UPDATE SKIP LOCKED, so overlapping sweeps just split the work.
+type ReservationSweeper struct {
+	db        *DB
+	interval  time.Duration
+	batchSize int
+	onExpired func(context.Context, models.ExpiredReservation)
+}
+
+// NewReservationSweeper builds a sweeper that checks for expired
+// reservations every interval, releasing up to batchSize orders per sweep.
+// onExpired is called once per order the sweep expires, after its seats
+// have already been released and it's been marked EXPIRED; callers use it
+// to notify the order's workflow and flight waitlist, which would
+// otherwise need this package to depend on the Temporal client.
+func NewReservationSweeper(db *DB, interval time.Duration, batchSize int, onExpired func(context.Context, models.ExpiredReservation)) *ReservationSweeper {
+	return &ReservationSweeper{db: db, interval: interval, batchSize: batchSize, onExpired: onExpired}
+}
+
+// Run sweeps on s.interval until ctx is cancelled. Meant to be started in
+// its own goroutine from main.
+func (s *ReservationSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweepOnce(ctx)
+		}
+	}
+}
+
+func (s *ReservationSweeper) sweepOnce(ctx context.Context) {
+	start := time.Now()
+	expired, err := s.db.CleanupExpiredReservations(ctx, s.batchSize)
+	sweepDurationSeconds.Set(time.Since(start).Seconds())
+	if err != nil {
+		return
+	}
+
+	for _, e := range expired {
+		seatsExpiredTotal.Add(int64(len(e.Seats)))
+		if s.onExpired != nil {
+			s.onExpired(ctx, e)
+		}
+	}
+}
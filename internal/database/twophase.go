@@ -0,0 +1,360 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"flight-booking-system/internal/models"
+)
+
+// PrepareReservation is the coordinator's prepare phase for a multi-flight
+// booking transaction: every leg's seats are locked and checked for
+// availability within a single transaction spanning the whole leg set, so
+// the FOR UPDATE locks taken for leg 1 are still held while leg 2 is
+// checked. Each leg that's satisfied is immediately flipped to the
+// SeatPending status (instead of left untouched) before that transaction
+// commits, so the hold survives past Prepare's own commit -- an ordinary
+// ReserveSeats treats SeatPending the same as any other unavailable
+// status, closing the window a concurrent single-flight booking would
+// otherwise have between Prepare and the eventual Commit/Abort. A
+// PREPARED transaction_logs row is appended per leg recording the
+// old->new status Commit will apply.
+//
+// A leg that can't be satisfied aborts the whole transaction, so either
+// every leg ends up PENDING and logged, or none do -- there's nothing for
+// a failed Prepare to clean up, and the caller can go straight to voting
+// Abort.
+func (db *DB) PrepareReservation(ctx context.Context, txnID, orderID, userID string, legs []models.LegRequest) ([]models.TransactionLogEntry, error) {
+	var entries []models.TransactionLogEntry
+
+	err := db.RunInTxn(ctx, true, func(tx *sql.Tx) error {
+		entries = make([]models.TransactionLogEntry, 0, len(legs))
+		for _, leg := range legs {
+			entry, err := prepareLegTxn(tx, txnID, orderID, userID, leg, db.ReservationTimeout)
+			if err != nil {
+				return fmt.Errorf("leg %s: %w", leg.FlightID, err)
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func prepareLegTxn(tx *sql.Tx, txnID, orderID, userID string, leg models.LegRequest, reservationTimeout time.Duration) (models.TransactionLogEntry, error) {
+	placeholders := strings.Repeat("?,", len(leg.Seats))
+	placeholders = placeholders[:len(placeholders)-1]
+
+	query := fmt.Sprintf(`
+		SELECT seat_number, status, reserved_by, reserved_at
+		FROM seats
+		WHERE flight_id = ? AND seat_number IN (%s)
+		FOR UPDATE
+	`, placeholders)
+
+	args := make([]interface{}, 0, len(leg.Seats)+1)
+	args = append(args, leg.FlightID)
+	for _, seat := range leg.Seats {
+		args = append(args, seat)
+	}
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return models.TransactionLogEntry{}, fmt.Errorf("failed to lock seats: %w", err)
+	}
+	defer rows.Close()
+
+	foundSeats := make(map[string]bool)
+	for rows.Next() {
+		var seatNumber, status string
+		var reservedBy sql.NullString
+		var reservedAt sql.NullTime
+		if err := rows.Scan(&seatNumber, &status, &reservedBy, &reservedAt); err != nil {
+			return models.TransactionLogEntry{}, fmt.Errorf("failed to scan seat: %w", err)
+		}
+		foundSeats[seatNumber] = true
+
+		if status == models.SeatPending && reservedBy.Valid && reservedBy.String == txnID {
+			continue // this txn's own retried/replayed Prepare already holds this seat
+		}
+		if status == models.SeatReserved && reservedAt.Valid && time.Since(reservedAt.Time) > reservationTimeout {
+			status = models.SeatAvailable // expired reservation, can be taken
+		}
+		if status != models.SeatAvailable {
+			return models.TransactionLogEntry{}, fmt.Errorf("seat %s: %w", seatNumber, ErrSeatNotAvailable)
+		}
+	}
+
+	for _, seat := range leg.Seats {
+		if !foundSeats[seat] {
+			return models.TransactionLogEntry{}, fmt.Errorf("seat %s: %w", seat, ErrSeatNotExist)
+		}
+	}
+
+	pendingQuery := fmt.Sprintf(`
+		UPDATE seats
+		SET status = ?, reserved_by = ?, user_id = ?, reserved_at = NOW()
+		WHERE flight_id = ? AND seat_number IN (%s)
+	`, placeholders)
+
+	pendingArgs := make([]interface{}, 0, len(leg.Seats)+4)
+	pendingArgs = append(pendingArgs, models.SeatPending, txnID, userID, leg.FlightID)
+	for _, seat := range leg.Seats {
+		pendingArgs = append(pendingArgs, seat)
+	}
+
+	if _, err := tx.Exec(pendingQuery, pendingArgs...); err != nil {
+		return models.TransactionLogEntry{}, fmt.Errorf("failed to mark seats pending: %w", err)
+	}
+
+	entry := models.TransactionLogEntry{
+		TxnID:     txnID,
+		FlightID:  leg.FlightID,
+		Seats:     leg.Seats,
+		OrderID:   orderID,
+		UserID:    userID,
+		OldStatus: models.SeatAvailable,
+		NewStatus: models.SeatReserved,
+		Status:    models.TxnLogPrepared,
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO transaction_logs (txn_id, flight_id, seats, order_id, user_id, old_status, new_status, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, entry.TxnID, entry.FlightID, strings.Join(entry.Seats, ","), entry.OrderID, entry.UserID,
+		entry.OldStatus, entry.NewStatus, entry.Status)
+	if err != nil {
+		return models.TransactionLogEntry{}, fmt.Errorf("failed to append transaction log: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.TransactionLogEntry{}, fmt.Errorf("failed to get transaction log id: %w", err)
+	}
+	entry.ID = id
+
+	return entry, nil
+}
+
+// Commit first records the coordinator's COMMIT decision in its own
+// transaction, then replays txnID's PREPARED log entries, applying each
+// leg's seat reservation and marking the log entries COMMITTED. Since the
+// decision lands before the replay, a crash between the two leaves
+// RecoverInFlightTransactions a recorded COMMIT to finish.
+func (db *DB) Commit(ctx context.Context, txnID string) error {
+	err := db.RunInTxn(ctx, true, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO transactions (txn_id, decision)
+			VALUES (?, ?)
+			ON DUPLICATE KEY UPDATE decision = VALUES(decision), updated_at = NOW()
+		`, txnID, models.TxnDecisionCommit)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record commit decision: %w", err)
+	}
+
+	return db.RunInTxn(ctx, true, func(tx *sql.Tx) error {
+		return replayTxn(tx, txnID, true)
+	})
+}
+
+// Abort replays txnID's PREPARED log entries, releasing each leg's
+// SeatPending hold back to SeatAvailable (Prepare marks seats pending as
+// soon as it locks them, so Abort has to undo that explicitly), then marks
+// the log entries ABORTED and records the coordinator's ABORT decision.
+func (db *DB) Abort(ctx context.Context, txnID string) error {
+	return db.RunInTxn(ctx, true, func(tx *sql.Tx) error {
+		return replayTxn(tx, txnID, false)
+	})
+}
+
+// replayTxn applies (commit) or discards (abort) every PREPARED log entry
+// for txnID and records the coordinator's decision. Every leg's seats are
+// re-locked and verified still SeatPending/reserved_by this txnID -- exactly
+// what Prepare left them as -- before being overwritten, instead of a blind
+// unconditional UPDATE, so a seat that somehow left that state between
+// Prepare and here is caught as a conflict rather than silently clobbered.
+func replayTxn(tx *sql.Tx, txnID string, apply bool) error {
+	rows, err := tx.Query(`
+		SELECT id, flight_id, seats, order_id, user_id, new_status
+		FROM transaction_logs
+		WHERE txn_id = ? AND status = ?
+	`, txnID, models.TxnLogPrepared)
+	if err != nil {
+		return fmt.Errorf("failed to read transaction log: %w", err)
+	}
+
+	type loggedLeg struct {
+		id        int64
+		flightID  string
+		seats     []string
+		orderID   string
+		userID    string
+		newStatus string
+	}
+	var legs []loggedLeg
+	for rows.Next() {
+		var l loggedLeg
+		var seatsCSV string
+		if err := rows.Scan(&l.id, &l.flightID, &seatsCSV, &l.orderID, &l.userID, &l.newStatus); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan transaction log: %w", err)
+		}
+		l.seats = strings.Split(seatsCSV, ",")
+		legs = append(legs, l)
+	}
+	rows.Close()
+
+	finalStatus := models.TxnLogAborted
+	decision := models.TxnDecisionAbort
+	if apply {
+		finalStatus = models.TxnLogCommitted
+		decision = models.TxnDecisionCommit
+	}
+
+	for _, l := range legs {
+		placeholders := strings.Repeat("?,", len(l.seats))
+		placeholders = placeholders[:len(placeholders)-1]
+
+		lockQuery := fmt.Sprintf(`
+			SELECT seat_number, status, reserved_by
+			FROM seats
+			WHERE flight_id = ? AND seat_number IN (%s)
+			FOR UPDATE
+		`, placeholders)
+		lockArgs := make([]interface{}, 0, len(l.seats)+1)
+		lockArgs = append(lockArgs, l.flightID)
+		for _, seat := range l.seats {
+			lockArgs = append(lockArgs, seat)
+		}
+
+		rows, err := tx.Query(lockQuery, lockArgs...)
+		if err != nil {
+			return fmt.Errorf("failed to lock leg %s seats: %w", l.flightID, err)
+		}
+		held := make(map[string]bool, len(l.seats))
+		for rows.Next() {
+			var seatNumber, status string
+			var reservedBy sql.NullString
+			if err := rows.Scan(&seatNumber, &status, &reservedBy); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan leg %s seat: %w", l.flightID, err)
+			}
+			if status == models.SeatPending && reservedBy.Valid && reservedBy.String == txnID {
+				held[seatNumber] = true
+			}
+		}
+		rows.Close()
+		for _, seat := range l.seats {
+			if !held[seat] {
+				return fmt.Errorf("leg %s seat %s: no longer pending for this transaction: %w",
+					l.flightID, seat, ErrSeatNotAvailable)
+			}
+		}
+
+		var updateErr error
+		if apply {
+			updateQuery := fmt.Sprintf(`
+				UPDATE seats
+				SET status = ?, reserved_by = ?, user_id = ?, reserved_at = NOW()
+				WHERE flight_id = ? AND seat_number IN (%s)
+			`, placeholders)
+
+			args := make([]interface{}, 0, len(l.seats)+4)
+			args = append(args, l.newStatus, l.orderID, l.userID, l.flightID)
+			for _, seat := range l.seats {
+				args = append(args, seat)
+			}
+			_, updateErr = tx.Exec(updateQuery, args...)
+		} else {
+			updateQuery := fmt.Sprintf(`
+				UPDATE seats
+				SET status = ?, reserved_by = NULL, user_id = NULL, reserved_at = NULL
+				WHERE flight_id = ? AND seat_number IN (%s)
+			`, placeholders)
+
+			args := make([]interface{}, 0, len(l.seats)+2)
+			args = append(args, models.SeatAvailable, l.flightID)
+			for _, seat := range l.seats {
+				args = append(args, seat)
+			}
+			_, updateErr = tx.Exec(updateQuery, args...)
+		}
+		if updateErr != nil {
+			return fmt.Errorf("failed to apply leg %s: %w", l.flightID, updateErr)
+		}
+
+		if _, err := tx.Exec(`UPDATE transaction_logs SET status = ? WHERE id = ?`, finalStatus, l.id); err != nil {
+			return fmt.Errorf("failed to update transaction log %d: %w", l.id, err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO transactions (txn_id, decision)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE decision = VALUES(decision), updated_at = NOW()
+	`, txnID, decision)
+	if err != nil {
+		return fmt.Errorf("failed to record transaction decision: %w", err)
+	}
+
+	return nil
+}
+
+// RecoverInFlightTransactions scans transaction_logs for txnIDs still
+// sitting PREPARED -- meaning the coordinator crashed somewhere between
+// voting and finishing replay -- and completes each one according to its
+// recorded decision, defaulting to Abort when no decision was ever
+// recorded (the coordinator crashed before reaching its commit point, the
+// same outcome as any leg voting NO). Meant to run once on startup, before
+// the worker or server starts picking up new work.
+func (db *DB) RecoverInFlightTransactions(ctx context.Context) (int, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT DISTINCT tl.txn_id, t.decision
+		FROM transaction_logs tl
+		LEFT JOIN transactions t ON t.txn_id = tl.txn_id
+		WHERE tl.status = ?
+	`, models.TxnLogPrepared)
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan in-flight transactions: %w", err)
+	}
+
+	type pending struct {
+		txnID    string
+		decision sql.NullString
+	}
+	var txns []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.txnID, &p.decision); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan in-flight transaction: %w", err)
+		}
+		txns = append(txns, p)
+	}
+	rows.Close()
+
+	recovered := 0
+	for _, p := range txns {
+		var err error
+		if p.decision.Valid && p.decision.String == models.TxnDecisionCommit {
+			err = db.Commit(ctx, p.txnID)
+		} else {
+			err = db.Abort(ctx, p.txnID)
+		}
+		if err != nil {
+			return recovered, fmt.Errorf("failed to recover transaction %s: %w", p.txnID, err)
+		}
+		recovered++
+	}
+
+	return recovered, nil
+}
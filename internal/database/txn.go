@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL error numbers that are safe to retry whole-transaction: the engine
+// itself gave up a lock (deadlock/timeout) or asked us to serialize again,
+// none of which imply the business logic inside fn was wrong.
+const (
+	errDeadlock         = 1213
+	errLockWaitTimeout  = 1205
+	errLockWaitTimeout2 = 1206 // legacy alias some MySQL forks still emit
+)
+
+const (
+	txnRetryInitialInterval = 50 * time.Millisecond
+	txnRetryBackoffFactor   = 2.0
+	txnRetryMaxInterval     = 2 * time.Second
+	txnRetryMaxAttempts     = 5
+	// txnDeadlineSlack is subtracted from the caller's deadline on every
+	// attempt so a per-attempt context never overruns it.
+	txnDeadlineSlack = 10 * time.Millisecond
+)
+
+// IsRetriable classifies an error returned from inside a RunInTxn closure.
+// Other packages can wrap it to extend the classification (e.g. to treat
+// their own sentinel errors as retriable) as long as they fall back to
+// calling this for anything they don't recognize. It's a variable so such
+// a wrapper can be assigned in its place; RunInTxn always calls the
+// current value.
+var IsRetriable = func(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case errDeadlock, errLockWaitTimeout, errLockWaitTimeout2:
+			return true
+		}
+	}
+	return false
+}
+
+// RunInTxn begins a transaction, invokes fn, and commits. If retryable is
+// true and fn (or the commit) fails with an error IsRetriable classifies as
+// transient, the whole closure is retried with exponential backoff (50ms
+// initial, factor 2, capped at 2s, up to 5 attempts); sentinel business
+// errors like ErrSeatNotAvailable are never retried regardless of the flag.
+// Each attempt gets its own context, shrunk by txnDeadlineSlack off the
+// caller's deadline (if any) so a retry loop can never run past it.
+func (db *DB) RunInTxn(ctx context.Context, retryable bool, fn func(*sql.Tx) error) error {
+	var lastErr error
+	backoff := txnRetryInitialInterval
+
+	for attempt := 1; attempt <= txnRetryMaxAttempts; attempt++ {
+		attemptCtx, cancel := attemptContext(ctx)
+		err := db.runOnce(attemptCtx, fn)
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		if !retryable || !IsRetriable(err) || attempt == txnRetryMaxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return lastErr
+		case <-time.After(jitter(backoff)):
+		}
+
+		backoff = time.Duration(float64(backoff) * txnRetryBackoffFactor)
+		if backoff > txnRetryMaxInterval {
+			backoff = txnRetryMaxInterval
+		}
+	}
+
+	return lastErr
+}
+
+func (db *DB) runOnce(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// attemptContext derives a per-attempt context from ctx, shrinking the
+// caller's deadline (if any) by txnDeadlineSlack.
+func attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, deadline.Add(-txnDeadlineSlack))
+}
+
+// jitter adds up to +/-20% random variance to a backoff duration so
+// concurrent retriers don't all wake up in lockstep.
+func jitter(d time.Duration) time.Duration {
+	variance := float64(d) * 0.2
+	return d + time.Duration(rand.Float64()*2*variance-variance)
+}
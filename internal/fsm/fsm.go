@@ -0,0 +1,175 @@
+// Package fsm provides a small, declarative finite-state-machine that
+// workflow packages can build on top of instead of hand-rolling
+// if/else branches over a status string. States declare which events
+// they accept and which Action runs in response; the FSM takes care of
+// recording the transition and invoking entry/exit hooks.
+package fsm
+
+import "fmt"
+
+// State identifies a node in the machine (e.g. "Created", "Confirmed").
+type State string
+
+// EventType identifies a transition trigger (e.g. "PaymentSucceeded").
+type EventType string
+
+// EventContext carries whatever an Action needs to do its work. Ctx holds
+// the caller's context (e.g. a workflow.Context), and Payload holds any
+// event-specific data (signal body, activity result, ...).
+type EventContext struct {
+	Event   EventType
+	Ctx     interface{}
+	Payload interface{}
+}
+
+// Action runs when an event is accepted in a given state and returns the
+// EventType that should drive the next transition.
+type Action func(EventContext) (EventType, error)
+
+// Hook runs on entry to or exit from a state.
+type Hook func(EventContext) error
+
+// StateTransitions maps a state to the events it accepts and the action
+// that handles each one.
+type StateTransitions map[State]map[EventType]Action
+
+// TransitionRecord is one row of the FSM's audit trail.
+type TransitionRecord struct {
+	From  State     `json:"from"`
+	To    State     `json:"to"`
+	Event EventType `json:"event"`
+	Seq   int       `json:"seq"`
+}
+
+// FSM is a pluggable-action state machine. Zero value is not usable; build
+// one with New.
+type FSM struct {
+	Current     State
+	Transitions StateTransitions
+	EntryHooks  map[State]Hook
+	ExitHooks   map[State]Hook
+
+	// ErrorState is the state entered when HandleError returns it.
+	ErrorState State
+	// HandleError, if set, is consulted whenever an Action returns an
+	// error; it returns the EventType to drive the machine into
+	// ErrorState (or any other declared transition).
+	HandleError func(err error, ec EventContext) EventType
+
+	// states is the full set of valid destinations: every state with its
+	// own declared transitions, plus initial and whatever terminalStates
+	// New was given. resolve() checks against this set instead of
+	// trusting any non-empty EventType, so firing a typo'd or unregistered
+	// event can't silently land the machine in a state nothing else
+	// recognizes.
+	states map[State]struct{}
+
+	log []TransitionRecord
+}
+
+// New builds an FSM starting in initial, with the given transition table.
+// terminalStates lists states reachable only as a destination -- ones with
+// no outgoing transitions of their own, like "Confirmed" or "Failed" --
+// since those would otherwise never appear as a key in transitions.
+func New(initial State, transitions StateTransitions, terminalStates ...State) *FSM {
+	states := make(map[State]struct{}, len(transitions)+len(terminalStates)+1)
+	states[initial] = struct{}{}
+	for s := range transitions {
+		states[s] = struct{}{}
+	}
+	for _, s := range terminalStates {
+		states[s] = struct{}{}
+	}
+	return &FSM{
+		Current:     initial,
+		Transitions: transitions,
+		EntryHooks:  make(map[State]Hook),
+		ExitHooks:   make(map[State]Hook),
+		states:      states,
+	}
+}
+
+// OnEnter registers a hook that runs after the FSM transitions into state.
+func (f *FSM) OnEnter(state State, hook Hook) {
+	f.EntryHooks[state] = hook
+}
+
+// OnExit registers a hook that runs before the FSM leaves state.
+func (f *FSM) OnExit(state State, hook Hook) {
+	f.ExitHooks[state] = hook
+}
+
+// Fire delivers an event to the machine. If the current state doesn't
+// accept the event, Fire returns an error without changing state. If the
+// event's Action errors and HandleError is set, the FSM transitions using
+// the EventType HandleError returns instead of propagating the error.
+func (f *FSM) Fire(ec EventContext) error {
+	accepted, ok := f.Transitions[f.Current]
+	if !ok {
+		return fmt.Errorf("fsm: state %q has no declared transitions", f.Current)
+	}
+
+	action, ok := accepted[ec.Event]
+	if !ok {
+		return fmt.Errorf("fsm: state %q does not accept event %q", f.Current, ec.Event)
+	}
+
+	next, err := action(ec)
+	if err != nil {
+		if f.HandleError == nil {
+			return fmt.Errorf("fsm: action for %q/%q failed: %w", f.Current, ec.Event, err)
+		}
+		next = f.HandleError(err, ec)
+	}
+
+	return f.transitionTo(next, ec)
+}
+
+// transitionTo moves the FSM to the state reached by following event from
+// the current state, running exit/entry hooks and appending to the log.
+func (f *FSM) transitionTo(event EventType, ec EventContext) error {
+	to, ok := f.resolve(event)
+	if !ok {
+		return fmt.Errorf("fsm: no state reachable via event %q from %q", event, f.Current)
+	}
+
+	if hook, ok := f.ExitHooks[f.Current]; ok {
+		if err := hook(ec); err != nil {
+			return fmt.Errorf("fsm: exit hook for %q failed: %w", f.Current, err)
+		}
+	}
+
+	from := f.Current
+	f.Current = to
+	f.log = append(f.log, TransitionRecord{From: from, To: to, Event: event, Seq: len(f.log)})
+
+	if hook, ok := f.EntryHooks[to]; ok {
+		if err := hook(ec); err != nil {
+			return fmt.Errorf("fsm: entry hook for %q failed: %w", to, err)
+		}
+	}
+
+	return nil
+}
+
+// resolve finds the state that declares itself reachable for event. States
+// are modeled so that the event name IS the target state (e.g. firing
+// EventType("Confirmed") moves the machine to State("Confirmed")), which
+// keeps the transition table declarative: entries only need to say which
+// events a state accepts, not where each one leads. Only states New was
+// told about (transition keys, initial, and terminalStates) are valid
+// destinations -- an event that doesn't name one of those is rejected
+// rather than silently becoming a new, unrecognized state.
+func (f *FSM) resolve(event EventType) (State, bool) {
+	target := State(event)
+	if _, ok := f.states[target]; ok {
+		return target, true
+	}
+	return "", false
+}
+
+// Log returns the ordered transition history for persistence alongside the
+// order (auditability).
+func (f *FSM) Log() []TransitionRecord {
+	return f.log
+}
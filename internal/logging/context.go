@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is the HTTP header LoggingMiddleware reads the
+// correlation ID from (generating one if absent) and echoes back to the
+// client.
+const RequestIDHeader = "X-Request-ID"
+
+type contextKey string
+
+const (
+	loggerContextKey    contextKey = "logger"
+	requestIDContextKey contextKey = "requestID"
+)
+
+// NewRequestID generates a fresh correlation ID for a request that didn't
+// carry one.
+func NewRequestID() string {
+	return uuid.New().String()
+}
+
+// NewContext attaches l to ctx so FromContext can recover it further down
+// the call stack.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey, l)
+}
+
+// FromContext recovers the Logger NewContext attached, or a fresh
+// "unscoped" root logger if none was (e.g. a call path that bypassed
+// LoggingMiddleware).
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(loggerContextKey).(*Logger); ok {
+		return l
+	}
+	return New("unscoped")
+}
+
+// WithRequestID stashes the correlation ID itself on ctx, independently of
+// the logger, so callers that need the raw ID (e.g. to put it on a
+// workflow's Memo) don't have to parse it back out of the logger.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+// RequestIDFromContext recovers the ID WithRequestID stashed, or "" if
+// none was set.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
@@ -0,0 +1,57 @@
+// Package logging provides a small lager-style structured logger on top of
+// log/slog: Session derives a child logger that carries extra fields
+// forever after, so a caller can hand a pre-scoped logger down its call
+// stack instead of threading correlation fields through every log call.
+package logging
+
+import (
+	"log/slog"
+	"os"
+)
+
+// Logger wraps a slog.Logger with the Session/Error shape the rest of the
+// codebase standardizes on.
+type Logger struct {
+	slog *slog.Logger
+}
+
+// New creates a root Logger tagged with name (the process or subsystem
+// emitting through it, e.g. "flight-booking-api" or "activities").
+func New(name string) *Logger {
+	return &Logger{slog: slog.New(slog.NewJSONHandler(os.Stdout, nil)).With("logger", name)}
+}
+
+// Session returns a child logger scoped to name, carrying fields alongside
+// everything the parent logger already carries. Calling Session again on
+// the result nests further (e.g. a per-request logger Session'd again per
+// activity call).
+func (l *Logger) Session(name string, fields map[string]interface{}) *Logger {
+	args := make([]interface{}, 0, len(fields)*2+2)
+	args = append(args, "session", name)
+	for k, v := range fields {
+		args = append(args, k, v)
+	}
+	return &Logger{slog: l.slog.With(args...)}
+}
+
+// Info logs msg with fields merged into whatever the logger already
+// carries.
+func (l *Logger) Info(msg string, fields map[string]interface{}) {
+	l.slog.Info(msg, flatten(fields)...)
+}
+
+// Error logs msg with err and fields, rather than folding the error into a
+// free-form message string.
+func (l *Logger) Error(msg string, err error, fields map[string]interface{}) {
+	args := flatten(fields)
+	args = append(args, "error", err)
+	l.slog.Error(msg, args...)
+}
+
+func flatten(fields map[string]interface{}) []interface{} {
+	args := make([]interface{}, 0, len(fields)*2)
+	for k, v := range fields {
+		args = append(args, k, v)
+	}
+	return args
+}
@@ -11,6 +11,8 @@ const (
 	StatusFailed         = "FAILED"
 	StatusExpired        = "EXPIRED"
 	StatusCancelled      = "CANCELLED"
+	StatusCompensating   = "COMPENSATING"
+	StatusRefunded       = "REFUNDED"
 )
 
 // Seat statuses
@@ -18,6 +20,29 @@ const (
 	SeatAvailable = "AVAILABLE"
 	SeatReserved  = "RESERVED"
 	SeatBooked    = "BOOKED"
+	// SeatPending marks a seat held by an in-flight 2PC PrepareReservation
+	// vote, before the coordinator's Commit/Abort decision lands. A normal
+	// single-flight ReserveSeats treats it the same as any other
+	// unavailable status, so the two paths can't double-book the same seat
+	// between Prepare and Commit.
+	SeatPending = "PENDING"
+)
+
+// Two-phase commit coordinator decisions, recorded durably in the
+// transactions table so a restarted server's recovery pass knows whether
+// an in-flight 2PC transaction should finish committing or aborting.
+const (
+	TxnDecisionCommit = "COMMIT"
+	TxnDecisionAbort  = "ABORT"
+)
+
+// Two-phase commit participant log statuses. A leg starts PREPARED (voted
+// yes, change not yet applied) and ends COMMITTED or ABORTED once Commit/
+// Abort has replayed it.
+const (
+	TxnLogPrepared  = "PREPARED"
+	TxnLogCommitted = "COMMITTED"
+	TxnLogAborted   = "ABORTED"
 )
 
 // Order represents a flight booking order
@@ -32,6 +57,20 @@ type Order struct {
 	UpdatedAt  time.Time `json:"updatedAt" db:"updated_at"`
 }
 
+// TransitionLogEntry is one row of a booking order's FSM transition
+// history (fsm.FSM.Log), persisted alongside the order by
+// DB.RecordTransitionLog so BookingWorkflow's state changes stay
+// auditable after the workflow itself has completed and fallen out of
+// Temporal's retention.
+type TransitionLogEntry struct {
+	OrderID   string    `json:"orderId" db:"order_id"`
+	Seq       int       `json:"seq" db:"seq"`
+	FromState string    `json:"from" db:"from_state"`
+	ToState   string    `json:"to" db:"to_state"`
+	Event     string    `json:"event" db:"event"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
 // Seat represents a flight seat
 type Seat struct {
 	SeatID     string     `json:"seatId" db:"seat_id"`
@@ -48,8 +87,10 @@ type Payment struct {
 	PaymentID     string    `json:"paymentId" db:"payment_id"`
 	OrderID       string    `json:"orderId" db:"order_id"`
 	PaymentCode   string    `json:"paymentCode" db:"payment_code"`
+	Provider      string    `json:"provider" db:"provider"`
 	TransactionID *string   `json:"transactionId,omitempty" db:"transaction_id"`
 	Status        string    `json:"status" db:"status"`
+	ErrorMessage  *string   `json:"errorMessage,omitempty" db:"error_message"`
 	Attempts      int       `json:"attempts" db:"attempts"`
 	CreatedAt     time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt     time.Time `json:"updatedAt" db:"updated_at"`
@@ -57,12 +98,71 @@ type Payment struct {
 
 // BookingInput represents workflow input
 type BookingInput struct {
-	OrderID  string   `json:"orderId"`
+	OrderID   string   `json:"orderId"`
+	FlightID  string   `json:"flightId"`
+	UserID    string   `json:"userId"`
+	Seats     []string `json:"seats"`
+	RequestID string   `json:"requestId,omitempty"`
+}
+
+// LegRequest is one leg of a multi-flight booking: the seats to reserve on
+// a single flight as part of a larger two-phase commit transaction.
+type LegRequest struct {
 	FlightID string   `json:"flightId"`
-	UserID   string   `json:"userId"`
 	Seats    []string `json:"seats"`
 }
 
+// TransactionLogEntry is one leg's prepared seat-status change for a 2PC
+// transaction (txnID), appended by DB.PrepareReservation and replayed by
+// DB.Commit/DB.Abort to either apply or discard it.
+type TransactionLogEntry struct {
+	ID        int64     `json:"id" db:"id"`
+	TxnID     string    `json:"txnId" db:"txn_id"`
+	FlightID  string    `json:"flightId" db:"flight_id"`
+	Seats     []string  `json:"seats" db:"-"`
+	OrderID   string    `json:"orderId" db:"order_id"`
+	UserID    string    `json:"userId" db:"user_id"`
+	OldStatus string    `json:"oldStatus" db:"old_status"`
+	NewStatus string    `json:"newStatus" db:"new_status"`
+	Status    string    `json:"status" db:"status"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// TransactionRecord is the coordinator's durable decision for one 2PC
+// transaction, written at the commit point so DB.RecoverInFlightTransactions
+// can finish a transaction whose coordinator crashed between deciding and
+// replaying it.
+type TransactionRecord struct {
+	TxnID     string    `json:"txnId" db:"txn_id"`
+	Decision  string    `json:"decision" db:"decision"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// MultiFlightBookingInput represents MultiFlightBookingWorkflow's input: a
+// single itinerary spanning one or more flight legs, booked atomically via
+// two-phase commit.
+type MultiFlightBookingInput struct {
+	ItineraryID string       `json:"itineraryId"`
+	UserID      string       `json:"userId"`
+	Legs        []LegRequest `json:"legs"`
+	RequestID   string       `json:"requestId,omitempty"`
+}
+
+// MultiFlightBookingState represents MultiFlightBookingWorkflow's queryable
+// state, the multi-leg analogue of BookingState.
+type MultiFlightBookingState struct {
+	ItineraryID string       `json:"itineraryId"`
+	UserID      string       `json:"userId"`
+	Legs        []LegRequest `json:"legs"`
+	Status      string       `json:"status"`
+}
+
+// MultiFlightBookingResult represents MultiFlightBookingWorkflow's output.
+type MultiFlightBookingResult struct {
+	State *MultiFlightBookingState `json:"state"`
+}
+
 // BookingState represents the current workflow state
 type BookingState struct {
 	OrderID            string    `json:"orderId"`
@@ -72,6 +172,11 @@ type BookingState struct {
 	Status             string    `json:"status"`
 	ReservationStartAt time.Time `json:"reservationStartAt"`
 	PaymentAttempts    int       `json:"paymentAttempts"`
+	// PaymentCode is kept only long enough to derive a saga compensation's
+	// idempotency key (orderID+paymentCode) if a later step fails after
+	// payment has already settled; it's not exposed via the status query.
+	PaymentCode       string `json:"-"`
+	PaymentTransferID string `json:"paymentTransferId,omitempty"`
 	// Note: TimeRemaining is NOT stored here because workflow.Now() is deterministic
 	// and doesn't advance during idle periods. Calculate it server-side using wall-clock time.
 }
@@ -113,16 +218,159 @@ type SubmitPaymentRequest struct {
 }
 
 type OrderStatusResponse struct {
-	OrderID       string   `json:"orderId"`
-	FlightID      string   `json:"flightId"`
-	UserID        string   `json:"userId"`
-	Seats         []string `json:"seats"`
-	Status        string   `json:"status"`
-	TimeRemaining int64    `json:"timeRemaining"` // seconds
-	ReservedAt    *time.Time `json:"reservedAt,omitempty"`
+	OrderID       string               `json:"orderId"`
+	FlightID      string               `json:"flightId"`
+	UserID        string               `json:"userId"`
+	Seats         []string             `json:"seats"`
+	Status        string               `json:"status"`
+	TimeRemaining int64                `json:"timeRemaining"` // seconds
+	ReservedAt    *time.Time           `json:"reservedAt,omitempty"`
+	TransitionLog []TransitionLogEntry `json:"transitionLog,omitempty"`
+}
+
+// CreateMultiOrderRequest books an itinerary spanning one or more flight
+// legs atomically: either every leg gets its requested seats, or none do.
+type CreateMultiOrderRequest struct {
+	UserID string       `json:"userId"`
+	Legs   []LegRequest `json:"legs"`
+}
+
+type CreateMultiOrderResponse struct {
+	ItineraryID string       `json:"itineraryId"`
+	UserID      string       `json:"userId"`
+	Legs        []LegRequest `json:"legs"`
+	Status      string       `json:"status"`
+	WorkflowID  string       `json:"workflowId"`
 }
 
 type SeatsResponse struct {
 	FlightID string `json:"flightId"`
 	Seats    []Seat `json:"seats"`
 }
+
+// WaitlistEntry represents one user's place in a flight's waitlist queue,
+// tracked inside WaitlistCoordinatorWorkflow.
+type WaitlistEntry struct {
+	EntryID        string    `json:"entryId"`
+	FlightID       string    `json:"flightId"`
+	UserID         string    `json:"userId"`
+	SeatsRequested int       `json:"seatsRequested"`
+	PreferredSeats []string  `json:"preferredSeats,omitempty"`
+	JoinedAt       time.Time `json:"joinedAt"`
+}
+
+type JoinWaitlistRequest struct {
+	UserID         string   `json:"userId"`
+	SeatsRequested int      `json:"seatsRequested"`
+	PreferredSeats []string `json:"preferredSeats,omitempty"`
+}
+
+type JoinWaitlistResponse struct {
+	EntryID  string `json:"entryId"`
+	FlightID string `json:"flightId"`
+	Position int    `json:"position"`
+}
+
+// WaitlistStatusResponse reports where an entry sits in the queue.
+type WaitlistStatusResponse struct {
+	EntryID              string `json:"entryId"`
+	Position             int    `json:"position"` // 0 means not found / already promoted
+	EstimatedWaitSeconds int64  `json:"estimatedWaitSeconds"`
+	PromotedOrderID      string `json:"promotedOrderId,omitempty"`
+}
+
+// Idempotency key statuses. A key starts IN_PROGRESS the moment
+// DB.ClaimIdempotencyKey wins the race to insert it, and moves to
+// COMPLETED once the handler it guarded has finished and
+// DB.CompleteIdempotencyRecord has stamped the response onto it.
+const (
+	IdempotencyStatusInProgress = "IN_PROGRESS"
+	IdempotencyStatusCompleted  = "COMPLETED"
+)
+
+// IdempotencyRecord is the persisted result of a mutating request made with
+// an Idempotency-Key header, keyed on (idempotency_key, route). A repeat
+// request within the TTL replays ResponseStatus/ResponseBody instead of
+// re-running the handler; a repeat with a different RequestHash is a 409;
+// a repeat that arrives while Status is still IN_PROGRESS (the original
+// request hasn't finished yet) is also a 409, since there's no response to
+// replay yet.
+type IdempotencyRecord struct {
+	Key            string    `json:"key" db:"idempotency_key"`
+	Route          string    `json:"route" db:"route"`
+	UserID         string    `json:"userId" db:"user_id"`
+	RequestHash    string    `json:"requestHash" db:"request_hash"`
+	Status         string    `json:"status" db:"status"`
+	ResponseStatus int       `json:"responseStatus" db:"response_status"`
+	ResponseBody   []byte    `json:"responseBody" db:"response_body"`
+	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
+}
+
+// SeatEvent is one row of the seat_events transactional outbox: a single
+// seat status transition (reserve, release, confirm, or expire), appended
+// in the same transaction as the seats-table change it describes so
+// database.SeatEventRelay can never publish something that didn't actually
+// happen. Seq is the outbox's AUTO_INCREMENT column; it's what
+// GET /flights/{flightId}/events resumes from via a Last-Event-ID header.
+type SeatEvent struct {
+	EventID    string    `json:"eventId" db:"event_id"`
+	Seq        int64     `json:"seq" db:"seq"`
+	FlightID   string    `json:"flightId" db:"flight_id"`
+	SeatNumber string    `json:"seatNumber" db:"seat_number"`
+	OldStatus  string    `json:"oldStatus" db:"old_status"`
+	NewStatus  string    `json:"newStatus" db:"new_status"`
+	OrderID    string    `json:"orderId" db:"order_id"`
+	OccurredAt time.Time `json:"occurredAt" db:"occurred_at"`
+}
+
+// ExpiredReservation is one order that database.DB.CleanupExpiredReservations
+// found past its reservation window and released, returned so the caller
+// (database.ReservationSweeper) can notify the order's owning workflow and
+// the flight's waitlist coordinator outside of the sweep transaction.
+type ExpiredReservation struct {
+	OrderID    string   `json:"orderId"`
+	FlightID   string   `json:"flightId"`
+	WorkflowID string   `json:"workflowId"`
+	RunID      string   `json:"runId"`
+	Seats      []string `json:"seats"`
+}
+
+// SubmitPaymentSignal is the payload delivered over SignalSubmitPayment. The
+// IdempotencyKey, forwarded from the SubmitPayment request's Idempotency-Key
+// header, is folded into the payment child workflow's ID so a client
+// retrying a slow submission can't start a second PaymentValidationWorkflow
+// (and thus a second charge) for the same attempt.
+type SubmitPaymentSignal struct {
+	PaymentCode    string `json:"paymentCode"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	RequestID      string `json:"requestId,omitempty"`
+}
+
+// Notification delivery channels
+const (
+	ChannelEmail   = "EMAIL"
+	ChannelSMS     = "SMS"
+	ChannelWebhook = "WEBHOOK"
+)
+
+// Delivery statuses
+const (
+	DeliveryStatusSent   = "SENT"
+	DeliveryStatusFailed = "FAILED"
+)
+
+// Delivery is one channel's attempt to notify a user about an order event
+// (currently just booking confirmation), recorded by
+// NotificationWorkflow/NotificationActivities so a failed channel's retry
+// schedule and outcome are visible outside the workflow.
+type Delivery struct {
+	DeliveryID        string     `json:"deliveryId" db:"id"`
+	OrderID           string     `json:"orderId" db:"order_id"`
+	Channel           string     `json:"channel" db:"channel"`
+	Status            string     `json:"status" db:"status"`
+	RetryCount        int        `json:"retryCount" db:"retry_count"`
+	NextDeliveryAt    *time.Time `json:"nextDeliveryAt,omitempty" db:"next_delivery_at"`
+	ExternalMessageID *string    `json:"externalMessageId,omitempty" db:"external_message_id"`
+	CreatedAt         time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updatedAt" db:"updated_at"`
+}
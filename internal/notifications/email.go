@@ -0,0 +1,29 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"flight-booking-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// EmailNotifier simulates an SMTP-backed confirmation email send.
+type EmailNotifier struct{}
+
+func NewEmailNotifier() *EmailNotifier { return &EmailNotifier{} }
+
+func (n *EmailNotifier) Channel() string { return models.ChannelEmail }
+
+func (n *EmailNotifier) Send(ctx context.Context, orderID, userID string) (string, error) {
+	time.Sleep(time.Duration(rand.Intn(300)) * time.Millisecond)
+
+	if rand.Float32() < 0.05 {
+		return "", errors.New("smtp: connection refused (simulated)")
+	}
+
+	return uuid.New().String(), nil
+}
@@ -0,0 +1,21 @@
+// Package notifications provides a pluggable channel abstraction for
+// delivering order notifications (currently just booking confirmation),
+// replacing the single log.Printf stub OrderActivities.SendConfirmation
+// used to be.
+package notifications
+
+import "context"
+
+// Notifier sends one notification attempt through a single channel.
+// Implementations are simulated in this repo the same way
+// internal/payments' connectors are, since there's no real SMTP/Twilio/
+// webhook endpoint to call here.
+type Notifier interface {
+	// Channel identifies which of models.Channel{Email,SMS,Webhook} this
+	// Notifier implements.
+	Channel() string
+
+	// Send notifies userID about orderID, returning a channel-assigned
+	// external message ID on success.
+	Send(ctx context.Context, orderID, userID string) (externalMessageID string, err error)
+}
@@ -0,0 +1,29 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"flight-booking-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// SMSNotifier simulates a Twilio-style SMS confirmation send.
+type SMSNotifier struct{}
+
+func NewSMSNotifier() *SMSNotifier { return &SMSNotifier{} }
+
+func (n *SMSNotifier) Channel() string { return models.ChannelSMS }
+
+func (n *SMSNotifier) Send(ctx context.Context, orderID, userID string) (string, error) {
+	time.Sleep(time.Duration(rand.Intn(300)) * time.Millisecond)
+
+	if rand.Float32() < 0.1 {
+		return "", errors.New("sms gateway rejected message (simulated)")
+	}
+
+	return uuid.New().String(), nil
+}
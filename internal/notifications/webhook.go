@@ -0,0 +1,30 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"flight-booking-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// WebhookNotifier simulates POSTing a confirmation event to a customer's
+// registered webhook URL.
+type WebhookNotifier struct{}
+
+func NewWebhookNotifier() *WebhookNotifier { return &WebhookNotifier{} }
+
+func (n *WebhookNotifier) Channel() string { return models.ChannelWebhook }
+
+func (n *WebhookNotifier) Send(ctx context.Context, orderID, userID string) (string, error) {
+	time.Sleep(time.Duration(rand.Intn(300)) * time.Millisecond)
+
+	if rand.Float32() < 0.1 {
+		return "", errors.New("webhook endpoint returned non-2xx (simulated)")
+	}
+
+	return uuid.New().String(), nil
+}
@@ -0,0 +1,127 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// BankTransferProvider simulates a Modulr/Mangopay-style bank transfer
+// connector: AuthorizePayment only initiates the transfer and returns
+// StatusPending immediately, and the transfer resolves to SUCCESS/FAILED
+// after a few GetTransferStatus polls -- the way a real bank rail settles
+// asynchronously instead of inline with the API call.
+type BankTransferProvider struct {
+	mu        sync.Mutex
+	transfers map[string]*pendingTransfer // keyed by idempotency key
+}
+
+type pendingTransfer struct {
+	result         TransferResult
+	pollsRemaining int
+	finalStatus    TransferStatus
+	finalError     string
+}
+
+func NewBankTransferProvider() *BankTransferProvider {
+	return &BankTransferProvider{transfers: make(map[string]*pendingTransfer)}
+}
+
+func (p *BankTransferProvider) Name() string { return "banktransfer" }
+
+func (p *BankTransferProvider) AuthorizePayment(ctx context.Context, req AuthorizeRequest) (*TransferResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if existing, ok := p.transfers[req.IdempotencyKey]; ok {
+		result := existing.result
+		return &result, nil
+	}
+
+	finalStatus := StatusSuccess
+	finalError := ""
+	if !paymentCodePattern.MatchString(req.PaymentCode) {
+		finalStatus = StatusFailed
+		finalError = "invalid payment code format (must be 5 digits)"
+	} else if rand.Float32() < 0.15 {
+		finalStatus = StatusFailed
+		finalError = "bank transfer declined (simulated)"
+	}
+
+	pending := &pendingTransfer{
+		result:         TransferResult{TransferID: uuid.New().String(), Status: StatusPending},
+		pollsRemaining: 1 + rand.Intn(3), // settles after 1-3 status polls
+		finalStatus:    finalStatus,
+		finalError:     finalError,
+	}
+	p.transfers[req.IdempotencyKey] = pending
+
+	result := pending.result
+	return &result, nil
+}
+
+func (p *BankTransferProvider) CapturePayment(ctx context.Context, idempotencyKey, transferID string) (*TransferResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pending, ok := p.transferByID(transferID)
+	if !ok {
+		return nil, fmt.Errorf("banktransfer: unknown transfer %s", transferID)
+	}
+	if pending.result.Status != StatusSuccess {
+		return nil, fmt.Errorf("banktransfer: cannot capture transfer %s in state %s", transferID, pending.result.Status)
+	}
+
+	result := pending.result
+	return &result, nil
+}
+
+func (p *BankTransferProvider) RefundPayment(ctx context.Context, idempotencyKey, transferID string) (*TransferResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pending, ok := p.transferByID(transferID)
+	if !ok {
+		return nil, fmt.Errorf("banktransfer: unknown transfer %s", transferID)
+	}
+
+	refunded := pending.result
+	refunded.Status = StatusSuccess
+	refunded.ErrorMessage = ""
+	return &refunded, nil
+}
+
+func (p *BankTransferProvider) GetTransferStatus(ctx context.Context, transferID string) (*TransferResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pending, ok := p.transferByID(transferID)
+	if !ok {
+		return nil, fmt.Errorf("banktransfer: unknown transfer %s", transferID)
+	}
+
+	if pending.result.Status == StatusPending {
+		pending.pollsRemaining--
+		if pending.pollsRemaining <= 0 {
+			pending.result.Status = pending.finalStatus
+			pending.result.ErrorMessage = pending.finalError
+		}
+	}
+
+	result := pending.result
+	return &result, nil
+}
+
+// transferByID scans the idempotency-keyed map for transferID. Callers must
+// hold p.mu.
+func (p *BankTransferProvider) transferByID(transferID string) (*pendingTransfer, bool) {
+	for _, t := range p.transfers {
+		if t.result.TransferID == transferID {
+			return t, true
+		}
+	}
+	return nil, false
+}
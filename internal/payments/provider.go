@@ -0,0 +1,71 @@
+// Package payments defines a pluggable connector abstraction for charging
+// an order's payment code against a real (or, here, simulated) payment
+// gateway, replacing the single hard-coded check that used to live in
+// PaymentActivities.ValidatePayment.
+package payments
+
+import "context"
+
+// Provider is a payment connector. AuthorizePayment may settle
+// synchronously (a Stripe-like card processor) or only initiate a transfer
+// that resolves later (a Modulr/Mangopay-style bank transfer) -- callers
+// tell which happened from the returned TransferResult.Status: a
+// synchronous provider's Authorize never returns StatusPending, so a
+// caller that always polls GetTransferStatus until Terminal works for
+// either kind.
+type Provider interface {
+	// Name identifies the provider for persistence/observability (e.g. the
+	// payments table's provider column).
+	Name() string
+
+	// AuthorizePayment places a hold for req.OrderID's payment, keyed by
+	// req.IdempotencyKey so a retried call with the same key returns the
+	// transfer it already started rather than charging twice.
+	AuthorizePayment(ctx context.Context, req AuthorizeRequest) (*TransferResult, error)
+
+	// CapturePayment settles a previously authorized transfer.
+	CapturePayment(ctx context.Context, idempotencyKey, transferID string) (*TransferResult, error)
+
+	// RefundPayment reverses a captured transfer.
+	RefundPayment(ctx context.Context, idempotencyKey, transferID string) (*TransferResult, error)
+
+	// GetTransferStatus reports transferID's current state -- the poll
+	// target for an async provider, and a cheap confirmation check for a
+	// synchronous one.
+	GetTransferStatus(ctx context.Context, transferID string) (*TransferResult, error)
+}
+
+// AuthorizeRequest carries everything a Provider needs to authorize a
+// payment, including the caller-derived idempotency key.
+type AuthorizeRequest struct {
+	IdempotencyKey string
+	OrderID        string
+	PaymentCode    string
+}
+
+// TransferStatus is the provider-agnostic lifecycle state of a transfer.
+type TransferStatus string
+
+const (
+	StatusPending TransferStatus = "PENDING"
+	StatusSuccess TransferStatus = "SUCCESS"
+	StatusFailed  TransferStatus = "FAILED"
+)
+
+// TransferResult is a Provider call's outcome, provider-agnostic so
+// PaymentValidationWorkflow doesn't need to know which Provider it's
+// talking to. ProviderName is filled in by PaymentActivities rather than
+// by the Provider itself, since it's the activity layer that knows which
+// Provider it invoked.
+type TransferResult struct {
+	ProviderName string         `json:"providerName"`
+	TransferID   string         `json:"transferId"`
+	Status       TransferStatus `json:"status"`
+	ErrorMessage string         `json:"errorMessage,omitempty"`
+}
+
+// Terminal reports whether Status is a final state a poll loop should stop
+// on.
+func (r *TransferResult) Terminal() bool {
+	return r.Status == StatusSuccess || r.Status == StatusFailed
+}
@@ -0,0 +1,110 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// paymentCodePattern matches the same 5-digit payment code format the
+// pre-connector ValidatePayment activity used to check inline.
+var paymentCodePattern = regexp.MustCompile(`^\d{5}$`)
+
+// StripeProvider simulates a card processor in the style of Stripe:
+// AuthorizePayment resolves synchronously, so GetTransferStatus only ever
+// reports back whatever Authorize/Capture already decided.
+type StripeProvider struct {
+	mu        sync.Mutex
+	transfers map[string]*TransferResult // keyed by idempotency key
+}
+
+func NewStripeProvider() *StripeProvider {
+	return &StripeProvider{transfers: make(map[string]*TransferResult)}
+}
+
+func (p *StripeProvider) Name() string { return "stripe" }
+
+func (p *StripeProvider) AuthorizePayment(ctx context.Context, req AuthorizeRequest) (*TransferResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if existing, ok := p.transfers[req.IdempotencyKey]; ok {
+		result := *existing
+		return &result, nil
+	}
+
+	result := &TransferResult{TransferID: uuid.New().String()}
+	switch {
+	case !paymentCodePattern.MatchString(req.PaymentCode):
+		result.Status = StatusFailed
+		result.ErrorMessage = "invalid payment code format (must be 5 digits)"
+	case rand.Float32() < 0.15:
+		result.Status = StatusFailed
+		result.ErrorMessage = "card declined (simulated)"
+	default:
+		result.Status = StatusSuccess
+	}
+
+	p.transfers[req.IdempotencyKey] = result
+
+	returned := *result
+	return &returned, nil
+}
+
+func (p *StripeProvider) CapturePayment(ctx context.Context, idempotencyKey, transferID string) (*TransferResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result, ok := p.transferByID(transferID)
+	if !ok {
+		return nil, fmt.Errorf("stripe: unknown transfer %s", transferID)
+	}
+	if result.Status != StatusSuccess {
+		return nil, fmt.Errorf("stripe: cannot capture transfer %s in state %s", transferID, result.Status)
+	}
+
+	returned := *result
+	return &returned, nil
+}
+
+func (p *StripeProvider) RefundPayment(ctx context.Context, idempotencyKey, transferID string) (*TransferResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result, ok := p.transferByID(transferID)
+	if !ok {
+		return nil, fmt.Errorf("stripe: unknown transfer %s", transferID)
+	}
+
+	refunded := *result
+	refunded.Status = StatusSuccess
+	return &refunded, nil
+}
+
+func (p *StripeProvider) GetTransferStatus(ctx context.Context, transferID string) (*TransferResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result, ok := p.transferByID(transferID)
+	if !ok {
+		return nil, fmt.Errorf("stripe: unknown transfer %s", transferID)
+	}
+
+	returned := *result
+	return &returned, nil
+}
+
+// transferByID scans the idempotency-keyed map for transferID. Callers must
+// hold p.mu.
+func (p *StripeProvider) transferByID(transferID string) (*TransferResult, bool) {
+	for _, r := range p.transfers {
+		if r.TransferID == transferID {
+			return r, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,50 @@
+package activities
+
+import (
+	"context"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+)
+
+// LoggingInterceptor logs start/end/duration/error uniformly for every
+// registered activity, so individual activities only need requestLogger
+// for the business-specific fields beyond that (order/flight/payment IDs).
+type LoggingInterceptor struct {
+	interceptor.WorkerInterceptorBase
+}
+
+func (i *LoggingInterceptor) InterceptActivity(
+	ctx context.Context, next interceptor.ActivityInboundInterceptor,
+) interceptor.ActivityInboundInterceptor {
+	return &loggingActivityInboundInterceptor{ActivityInboundInterceptorBase: interceptor.ActivityInboundInterceptorBase{Next: next}}
+}
+
+type loggingActivityInboundInterceptor struct {
+	interceptor.ActivityInboundInterceptorBase
+}
+
+func (i *loggingActivityInboundInterceptor) ExecuteActivity(
+	ctx context.Context, in *interceptor.ExecuteActivityInput,
+) (interface{}, error) {
+	info := activity.GetInfo(ctx)
+	logger := rootLogger.Session(info.ActivityType.Name, map[string]interface{}{
+		"workflow_id": info.WorkflowExecution.ID,
+		"run_id":      info.WorkflowExecution.RunID,
+		"attempt":     info.Attempt,
+	})
+	logger.Info("activity started", nil)
+
+	start := time.Now()
+	result, err := i.Next.ExecuteActivity(ctx, in)
+	fields := map[string]interface{}{"duration_ms": time.Since(start).Milliseconds()}
+
+	if err != nil {
+		logger.Error("activity failed", err, fields)
+		return result, err
+	}
+
+	logger.Info("activity completed", fields)
+	return result, nil
+}
@@ -0,0 +1,32 @@
+package activities
+
+import (
+	"context"
+
+	"flight-booking-system/internal/logging"
+	"flight-booking-system/internal/temporal/correlation"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// rootLogger is the activities package's root structured logger; each
+// activity invocation Sessions a child off of it via requestLogger.
+var rootLogger = logging.New("activities")
+
+// requestLogger builds a session logger for one activity invocation,
+// folding in the request ID correlation.NewPropagator forwarded from the
+// workflow that invoked it, the invoking workflow's ID/run ID (so a single
+// booking's trail can be grep'd across worker restarts), and whatever
+// fields the activity itself already knows (order/flight/user IDs).
+func requestLogger(ctx context.Context, activityName string, fields map[string]interface{}) *logging.Logger {
+	if fields == nil {
+		fields = map[string]interface{}{}
+	}
+	if requestID := correlation.RequestIDFromContext(ctx); requestID != "" {
+		fields["request_id"] = requestID
+	}
+	info := activity.GetInfo(ctx)
+	fields["workflow_id"] = info.WorkflowExecution.ID
+	fields["run_id"] = info.WorkflowExecution.RunID
+	return rootLogger.Session(activityName, fields)
+}
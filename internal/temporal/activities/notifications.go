@@ -0,0 +1,182 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"flight-booking-system/internal/database"
+	"flight-booking-system/internal/models"
+	"flight-booking-system/internal/notifications"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/activity"
+)
+
+// deliveryRetryBackoff is how far out NextDeliveryAt is set on a failed
+// delivery, both when NotificationActivities.send first exhausts its
+// Temporal-level retries and when NotificationRetrier's own requeue attempt
+// fails again. NotificationRetrier is what reads it.
+const deliveryRetryBackoff = 5 * time.Minute
+
+type NotificationActivities struct {
+	DB      *database.DB
+	Email   notifications.Notifier
+	SMS     notifications.Notifier
+	Webhook notifications.Notifier
+}
+
+func NewNotificationActivities(db *database.DB, email, sms, webhook notifications.Notifier) *NotificationActivities {
+	return &NotificationActivities{DB: db, Email: email, SMS: sms, Webhook: webhook}
+}
+
+// SendEmailConfirmation notifies the order's owner by email.
+func (a *NotificationActivities) SendEmailConfirmation(ctx context.Context, orderID, userID string) error {
+	return a.send(ctx, a.Email, orderID, userID)
+}
+
+// SendSMSConfirmation notifies the order's owner by SMS.
+func (a *NotificationActivities) SendSMSConfirmation(ctx context.Context, orderID, userID string) error {
+	return a.send(ctx, a.SMS, orderID, userID)
+}
+
+// SendWebhookConfirmation notifies the order's owner's registered webhook.
+func (a *NotificationActivities) SendWebhookConfirmation(ctx context.Context, orderID, userID string) error {
+	return a.send(ctx, a.Webhook, orderID, userID)
+}
+
+// send runs notifier, records the resulting Delivery row regardless of
+// outcome, and returns an error only when the send itself failed -- the
+// activity's own retry policy governs re-attempts within the workflow
+// (RetryCount records how many Temporal had already made when this row was
+// written), and NextDeliveryAt on a failure hands the delivery off to
+// NotificationRetrier once those are exhausted.
+func (a *NotificationActivities) send(ctx context.Context, notifier notifications.Notifier, orderID, userID string) error {
+	logger := requestLogger(ctx, "SendConfirmation", map[string]interface{}{"order_id": orderID, "channel": notifier.Channel()})
+
+	delivery := &models.Delivery{
+		DeliveryID: uuid.New().String(),
+		OrderID:    orderID,
+		Channel:    notifier.Channel(),
+		Status:     models.DeliveryStatusSent,
+		RetryCount: activity.GetInfo(ctx).Attempt - 1,
+	}
+
+	externalMessageID, sendErr := notifier.Send(ctx, orderID, userID)
+	if sendErr != nil {
+		delivery.Status = models.DeliveryStatusFailed
+		nextAt := time.Now().Add(deliveryRetryBackoff)
+		delivery.NextDeliveryAt = &nextAt
+	} else {
+		delivery.ExternalMessageID = &externalMessageID
+	}
+
+	if err := a.DB.RecordDelivery(ctx, delivery); err != nil {
+		logger.Error("failed to record delivery", err, nil)
+	}
+
+	if sendErr != nil {
+		logger.Error("failed to send confirmation", sendErr, nil)
+		return fmt.Errorf("failed to send %s confirmation: %w", notifier.Channel(), sendErr)
+	}
+
+	logger.Info("sent confirmation", map[string]interface{}{"external_message_id": externalMessageID})
+	return nil
+}
+
+// NotificationRetrier requeues FAILED deliveries whose NextDeliveryAt has
+// arrived by resending through the same channel that first failed. It
+// polls the deliveries table on a plain interval, the same way
+// database.ReservationSweeper and database.SeatEventRelay run outside
+// Temporal, rather than as an activity: by the time a delivery's Temporal-
+// level attempts are exhausted, the NotificationWorkflow that ran it has
+// already completed, so there's no running workflow left to drive a
+// Temporal-level retry.
+type NotificationRetrier struct {
+	DB         *database.DB
+	notifiers  map[string]notifications.Notifier
+	maxRetries int
+}
+
+// NewNotificationRetrier builds a retrier that resends through whichever
+// of email/sms/webhook a FAILED delivery's Channel names, giving up for
+// good once a failed attempt's RetryCount has reached maxRetries.
+func NewNotificationRetrier(db *database.DB, email, sms, webhook notifications.Notifier, maxRetries int) *NotificationRetrier {
+	return &NotificationRetrier{
+		DB: db,
+		notifiers: map[string]notifications.Notifier{
+			email.Channel():   email,
+			sms.Channel():     sms,
+			webhook.Channel(): webhook,
+		},
+		maxRetries: maxRetries,
+	}
+}
+
+// Run polls for deliveries due for retry every interval until ctx is
+// cancelled. Meant to be started in its own goroutine from main.
+func (r *NotificationRetrier) Run(ctx context.Context, interval time.Duration, batchSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.retryOnce(ctx, batchSize)
+		}
+	}
+}
+
+func (r *NotificationRetrier) retryOnce(ctx context.Context, batchSize int) {
+	due, err := r.DB.PollDeliveriesDueForRetry(ctx, batchSize)
+	if err != nil {
+		return
+	}
+
+	for _, d := range due {
+		r.retry(ctx, d)
+	}
+}
+
+// retry resends d through its original channel and records a fresh
+// Delivery row for the attempt, then clears d's own NextDeliveryAt so the
+// next poll doesn't pick the same row up again.
+func (r *NotificationRetrier) retry(ctx context.Context, d models.Delivery) {
+	notifier, ok := r.notifiers[d.Channel]
+	if !ok {
+		return
+	}
+
+	order, err := r.DB.GetOrder(d.OrderID)
+	if err != nil {
+		return
+	}
+
+	if err := r.DB.ClearDeliveryRetry(ctx, d.DeliveryID); err != nil {
+		return
+	}
+
+	retryCount := d.RetryCount + 1
+	delivery := &models.Delivery{
+		DeliveryID: uuid.New().String(),
+		OrderID:    d.OrderID,
+		Channel:    d.Channel,
+		Status:     models.DeliveryStatusSent,
+		RetryCount: retryCount,
+	}
+
+	externalMessageID, sendErr := notifier.Send(ctx, d.OrderID, order.UserID)
+	if sendErr != nil {
+		delivery.Status = models.DeliveryStatusFailed
+		if retryCount < r.maxRetries {
+			nextAt := time.Now().Add(deliveryRetryBackoff)
+			delivery.NextDeliveryAt = &nextAt
+		}
+	} else {
+		delivery.ExternalMessageID = &externalMessageID
+	}
+
+	r.DB.RecordDelivery(ctx, delivery)
+}
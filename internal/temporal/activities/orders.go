@@ -4,9 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 
 	"flight-booking-system/internal/database"
+	"flight-booking-system/internal/models"
 	"go.temporal.io/sdk/temporal"
 )
 
@@ -20,24 +20,54 @@ func NewOrderActivities(db *database.DB) *OrderActivities {
 
 // UpdateOrderStatus updates an order's status
 func (a *OrderActivities) UpdateOrderStatus(ctx context.Context, orderID, status string) error {
+	logger := requestLogger(ctx, "UpdateOrderStatus", map[string]interface{}{"order_id": orderID, "status": status})
+
 	err := a.DB.UpdateOrderStatus(orderID, status)
 	if err != nil {
 		// Order not found is a permanent error - don't retry
 		if errors.Is(err, database.ErrOrderNotFound) {
+			logger.Error("order not found", err, nil)
 			return temporal.NewNonRetryableApplicationError(
 				err.Error(),
 				"OrderNotFound",
 				err,
 			)
 		}
+		logger.Error("failed to update order status", err, nil)
 		return fmt.Errorf("failed to update order status: %w", err)
 	}
+
+	logger.Info("updated order status", nil)
 	return nil
 }
 
-// SendConfirmation sends a booking confirmation (simulated)
-func (a *OrderActivities) SendConfirmation(ctx context.Context, orderID string) error {
-	// In production, this would send an email/SMS
-	log.Printf("Sending confirmation for order %s", orderID)
+// CreateOrder persists a new order record. Used by BookingWorkflow's
+// callers today via the API handler directly, and by
+// WaitlistCoordinatorWorkflow when it promotes a queued entry into a
+// freshly-started BookingWorkflow.
+func (a *OrderActivities) CreateOrder(ctx context.Context, order *models.Order) error {
+	logger := requestLogger(ctx, "CreateOrder", map[string]interface{}{"order_id": order.OrderID})
+
+	if err := a.DB.CreateOrder(order); err != nil {
+		logger.Error("failed to create order", err, nil)
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+
+	logger.Info("created order", nil)
+	return nil
+}
+
+// RecordTransitionLog persists a completed booking order's full FSM
+// transition history for auditability, once BookingWorkflow has reached a
+// terminal state or failed.
+func (a *OrderActivities) RecordTransitionLog(ctx context.Context, entries []models.TransitionLogEntry) error {
+	logger := requestLogger(ctx, "RecordTransitionLog", map[string]interface{}{"entries": len(entries)})
+
+	if err := a.DB.RecordTransitionLog(ctx, entries); err != nil {
+		logger.Error("failed to record transition log", err, nil)
+		return fmt.Errorf("failed to record transition log: %w", err)
+	}
+
+	logger.Info("recorded transition log", nil)
 	return nil
 }
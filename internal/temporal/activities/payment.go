@@ -2,100 +2,124 @@ package activities
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"math/rand"
-	"regexp"
-	"time"
 
 	"flight-booking-system/internal/database"
 	"flight-booking-system/internal/models"
+	"flight-booking-system/internal/payments"
 
 	"github.com/google/uuid"
 )
 
 type PaymentActivities struct {
-	DB *database.DB
+	DB       *database.DB
+	Provider payments.Provider
 }
 
-func NewPaymentActivities(db *database.DB) *PaymentActivities {
-	return &PaymentActivities{DB: db}
+func NewPaymentActivities(db *database.DB, provider payments.Provider) *PaymentActivities {
+	return &PaymentActivities{DB: db, Provider: provider}
 }
 
-// ValidatePayment validates a payment code with simulated failures
-func (a *PaymentActivities) ValidatePayment(ctx context.Context, paymentCode string, orderID string) (*models.PaymentResult, error) {
-	// Validate payment code format (5 digits)
-	matched, err := regexp.MatchString(`^\d{5}$`, paymentCode)
+// transferIdempotencyKey derives a deterministic idempotency key from
+// orderID+paymentCode, so a retried Authorize/Capture activity invocation
+// attaches to the same provider-side transfer instead of starting a new
+// one and double-charging.
+func transferIdempotencyKey(orderID, paymentCode string) string {
+	return orderID + ":" + paymentCode
+}
+
+// AuthorizePayment places a hold for orderID's payment through the
+// configured Provider. A synchronous provider (e.g. StripeProvider)
+// settles this immediately; an async one (e.g. BankTransferProvider)
+// returns payments.StatusPending and PaymentValidationWorkflow polls
+// GetTransferStatus until it settles.
+func (a *PaymentActivities) AuthorizePayment(ctx context.Context, orderID, paymentCode string) (*payments.TransferResult, error) {
+	logger := requestLogger(ctx, "AuthorizePayment", map[string]interface{}{"order_id": orderID, "provider": a.Provider.Name()})
+
+	result, err := a.Provider.AuthorizePayment(ctx, payments.AuthorizeRequest{
+		IdempotencyKey: transferIdempotencyKey(orderID, paymentCode),
+		OrderID:        orderID,
+		PaymentCode:    paymentCode,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("regex error: %w", err)
-	}
-	if !matched {
-		return &models.PaymentResult{
-			Success:      false,
-			ErrorMessage: "invalid payment code format (must be 5 digits)",
-		}, nil
+		logger.Error("failed to authorize payment", err, nil)
+		return nil, fmt.Errorf("failed to authorize payment: %w", err)
 	}
+	result.ProviderName = a.Provider.Name()
 
-	// Simulate random delay (0-5 seconds)
-	delay := time.Duration(rand.Intn(5000)) * time.Millisecond
-	time.Sleep(delay)
+	logger.Info("authorized payment", map[string]interface{}{"transfer_id": result.TransferID, "status": result.Status})
+	return result, nil
+}
 
-	// Simulate 15% failure rate
-	if rand.Float32() < 0.15 {
-		return &models.PaymentResult{
-			Success:      false,
-			ErrorMessage: "payment gateway error (simulated)",
-		}, errors.New("payment gateway error")
+// GetTransferStatus polls the configured Provider for transferID's current
+// state. PaymentValidationWorkflow's poll loop calls this until the result
+// is terminal.
+func (a *PaymentActivities) GetTransferStatus(ctx context.Context, transferID string) (*payments.TransferResult, error) {
+	result, err := a.Provider.GetTransferStatus(ctx, transferID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer status: %w", err)
 	}
+	result.ProviderName = a.Provider.Name()
+	return result, nil
+}
 
-	// Generate transaction ID
-	transactionID := uuid.New().String()
+// CapturePayment settles an authorized transfer once it's reached
+// payments.StatusSuccess.
+func (a *PaymentActivities) CapturePayment(ctx context.Context, orderID, paymentCode, transferID string) (*payments.TransferResult, error) {
+	logger := requestLogger(ctx, "CapturePayment", map[string]interface{}{"order_id": orderID, "transfer_id": transferID})
 
-	return &models.PaymentResult{
-		Success:       true,
-		TransactionID: transactionID,
-	}, nil
+	result, err := a.Provider.CapturePayment(ctx, transferIdempotencyKey(orderID, paymentCode), transferID)
+	if err != nil {
+		logger.Error("failed to capture payment", err, nil)
+		return nil, fmt.Errorf("failed to capture payment: %w", err)
+	}
+	result.ProviderName = a.Provider.Name()
+
+	logger.Info("captured payment", map[string]interface{}{"transfer_id": result.TransferID})
+	return result, nil
 }
 
-// UpdatePaymentRecord creates or updates a payment record with the result
-func (a *PaymentActivities) UpdatePaymentRecord(ctx context.Context, orderID, paymentCode, status string, transactionID *string, errorMessage *string) error {
-	// First, try to update existing record
-	updateQuery := `
-		UPDATE payments
-		SET status = ?, transaction_id = ?, error_message = ?, updated_at = NOW()
-		WHERE order_id = ?
-		ORDER BY created_at DESC
-		LIMIT 1
-	`
-
-	result, err := a.DB.Exec(updateQuery, status, transactionID, errorMessage, orderID)
+// RefundPayment reverses a previously captured transfer. BookingWorkflow's
+// saga compensation calls this when a later step (e.g. ConfirmSeats) fails
+// after payment has already settled, so the customer isn't left charged
+// for a booking that never confirmed.
+func (a *PaymentActivities) RefundPayment(ctx context.Context, orderID, paymentCode, transferID string) (*payments.TransferResult, error) {
+	logger := requestLogger(ctx, "RefundPayment", map[string]interface{}{"order_id": orderID, "transfer_id": transferID})
+
+	result, err := a.Provider.RefundPayment(ctx, transferIdempotencyKey(orderID, paymentCode), transferID)
 	if err != nil {
-		return fmt.Errorf("failed to update payment record: %w", err)
+		logger.Error("failed to refund payment", err, nil)
+		return nil, fmt.Errorf("failed to refund payment: %w", err)
 	}
+	result.ProviderName = a.Provider.Name()
 
-	// If no rows were updated, create a new record
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to check rows affected: %w", err)
+	logger.Info("refunded payment", map[string]interface{}{"transfer_id": result.TransferID})
+	return result, nil
+}
+
+// UpdatePaymentRecord creates or updates a payment record with the result,
+// including which provider handled it and its external transfer reference.
+// The update-or-insert runs through DB.UpdateOrCreatePayment, which uses
+// RunInTxn to retry on transient lock contention instead of letting it
+// surface as an activity failure that burns a Temporal retry.
+func (a *PaymentActivities) UpdatePaymentRecord(ctx context.Context, orderID, paymentCode, provider, status string, transactionID *string, errorMessage *string) error {
+	logger := requestLogger(ctx, "UpdatePaymentRecord", map[string]interface{}{"order_id": orderID, "status": status, "provider": provider})
+
+	payment := &models.Payment{
+		PaymentID:     uuid.New().String(),
+		OrderID:       orderID,
+		PaymentCode:   paymentCode,
+		Provider:      provider,
+		Status:        status,
+		TransactionID: transactionID,
+		ErrorMessage:  errorMessage,
 	}
 
-	if rowsAffected == 0 {
-		// Create new payment record
-		paymentID := uuid.New().String()
-		payment := &models.Payment{
-			PaymentID:     paymentID,
-			OrderID:       orderID,
-			PaymentCode:   paymentCode,
-			Status:        status,
-			TransactionID: transactionID,
-			ErrorMessage:  errorMessage,
-		}
-
-		err := a.DB.CreatePayment(payment)
-		if err != nil {
-			return fmt.Errorf("failed to create payment record: %w", err)
-		}
+	if err := a.DB.UpdateOrCreatePayment(ctx, payment); err != nil {
+		logger.Error("failed to update payment record", err, nil)
+		return fmt.Errorf("failed to update payment record: %w", err)
 	}
 
+	logger.Info("updated payment record", nil)
 	return nil
 }
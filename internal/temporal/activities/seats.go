@@ -2,9 +2,12 @@ package activities
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"flight-booking-system/internal/database"
+
+	"go.temporal.io/sdk/temporal"
 )
 
 type SeatActivities struct {
@@ -17,36 +20,64 @@ func NewSeatActivities(db *database.DB) *SeatActivities {
 
 // ReserveSeats reserves seats for an order
 func (a *SeatActivities) ReserveSeats(ctx context.Context, flightID string, seats []string, orderID, userID string) error {
-	err := a.DB.ReserveSeats(flightID, seats, orderID, userID)
+	logger := requestLogger(ctx, "ReserveSeats", map[string]interface{}{
+		"order_id": orderID, "flight_id": flightID, "user_id": userID,
+	})
+
+	err := a.DB.ReserveSeats(ctx, flightID, seats, orderID, userID)
 	if err != nil {
+		// Seat gone/never-existed is a business failure, not transient
+		// contention - don't burn the activity's retry budget on it.
+		if errors.Is(err, database.ErrSeatNotAvailable) || errors.Is(err, database.ErrSeatNotExist) {
+			logger.Info("seat unavailable, not retrying", map[string]interface{}{"seats": seats, "error": err.Error()})
+			return temporal.NewNonRetryableApplicationError(err.Error(), "SeatUnavailable", err)
+		}
+		logger.Error("failed to reserve seats", err, map[string]interface{}{"seats": seats})
 		return fmt.Errorf("failed to reserve seats: %w", err)
 	}
+
+	logger.Info("reserved seats", map[string]interface{}{"seats": seats})
 	return nil
 }
 
 // ReleaseSeats releases seats reserved by an order
 func (a *SeatActivities) ReleaseSeats(ctx context.Context, orderID string) error {
-	err := a.DB.ReleaseSeats(orderID)
+	logger := requestLogger(ctx, "ReleaseSeats", map[string]interface{}{"order_id": orderID})
+
+	err := a.DB.ReleaseSeats(ctx, orderID)
 	if err != nil {
+		logger.Error("failed to release seats", err, nil)
 		return fmt.Errorf("failed to release seats: %w", err)
 	}
+
+	logger.Info("released seats", nil)
 	return nil
 }
 
 // UpdateSeats updates seat selection for an order
 func (a *SeatActivities) UpdateSeats(ctx context.Context, orderID string, oldSeats, newSeats []string) error {
-	err := a.DB.UpdateSeats(orderID, oldSeats, newSeats)
+	logger := requestLogger(ctx, "UpdateSeats", map[string]interface{}{"order_id": orderID})
+
+	err := a.DB.UpdateSeats(ctx, orderID, oldSeats, newSeats)
 	if err != nil {
+		logger.Error("failed to update seats", err, map[string]interface{}{"oldSeats": oldSeats, "newSeats": newSeats})
 		return fmt.Errorf("failed to update seats: %w", err)
 	}
+
+	logger.Info("updated seats", map[string]interface{}{"oldSeats": oldSeats, "newSeats": newSeats})
 	return nil
 }
 
 // ConfirmSeats confirms seats for an order (mark as BOOKED)
 func (a *SeatActivities) ConfirmSeats(ctx context.Context, orderID string) error {
-	err := a.DB.ConfirmSeats(orderID)
+	logger := requestLogger(ctx, "ConfirmSeats", map[string]interface{}{"order_id": orderID})
+
+	err := a.DB.ConfirmSeats(ctx, orderID)
 	if err != nil {
+		logger.Error("failed to confirm seats", err, nil)
 		return fmt.Errorf("failed to confirm seats: %w", err)
 	}
+
+	logger.Info("confirmed seats", nil)
 	return nil
 }
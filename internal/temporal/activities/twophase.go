@@ -0,0 +1,73 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"flight-booking-system/internal/database"
+	"flight-booking-system/internal/models"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// TwoPhaseActivities wraps DB's 2PC coordinator/participant operations so
+// MultiFlightBookingWorkflow can drive them as ordinary Temporal activities
+// instead of reaching into the database directly.
+type TwoPhaseActivities struct {
+	DB *database.DB
+}
+
+func NewTwoPhaseActivities(db *database.DB) *TwoPhaseActivities {
+	return &TwoPhaseActivities{DB: db}
+}
+
+// PrepareReservation is the 2PC vote phase for one multi-flight booking
+// transaction. A seat-unavailable/seat-not-exist failure is non-retryable
+// -- retrying won't make a taken seat free -- so the workflow can abort
+// immediately instead of burning activity retries on a vote that will
+// never change.
+func (a *TwoPhaseActivities) PrepareReservation(ctx context.Context, txnID, orderID, userID string, legs []models.LegRequest) ([]models.TransactionLogEntry, error) {
+	logger := requestLogger(ctx, "PrepareReservation", map[string]interface{}{"txn_id": txnID, "order_id": orderID})
+
+	entries, err := a.DB.PrepareReservation(ctx, txnID, orderID, userID, legs)
+	if err != nil {
+		if errors.Is(err, database.ErrSeatNotAvailable) || errors.Is(err, database.ErrSeatNotExist) {
+			logger.Error("leg unavailable, voting to abort", err, map[string]interface{}{"prepared_legs": len(entries)})
+			return entries, temporal.NewNonRetryableApplicationError(err.Error(), "SeatUnavailable", err)
+		}
+		logger.Error("failed to prepare reservation", err, nil)
+		return entries, fmt.Errorf("failed to prepare reservation: %w", err)
+	}
+
+	logger.Info("prepared all legs", map[string]interface{}{"legs": len(entries)})
+	return entries, nil
+}
+
+// Commit applies txnID's prepared seat changes and marks the transaction
+// committed.
+func (a *TwoPhaseActivities) Commit(ctx context.Context, txnID string) error {
+	logger := requestLogger(ctx, "Commit", map[string]interface{}{"txn_id": txnID})
+
+	if err := a.DB.Commit(ctx, txnID); err != nil {
+		logger.Error("failed to commit transaction", err, nil)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Info("committed transaction", nil)
+	return nil
+}
+
+// Abort discards txnID's prepared seat changes and marks the transaction
+// aborted.
+func (a *TwoPhaseActivities) Abort(ctx context.Context, txnID string) error {
+	logger := requestLogger(ctx, "Abort", map[string]interface{}{"txn_id": txnID})
+
+	if err := a.DB.Abort(ctx, txnID); err != nil {
+		logger.Error("failed to abort transaction", err, nil)
+		return fmt.Errorf("failed to abort transaction: %w", err)
+	}
+
+	logger.Info("aborted transaction", nil)
+	return nil
+}
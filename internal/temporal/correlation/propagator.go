@@ -0,0 +1,105 @@
+// Package correlation carries the HTTP request ID set by
+// api.LoggingMiddleware across the client -> workflow -> activity and
+// workflow -> child-workflow boundaries, via Temporal's ContextPropagator
+// mechanism, so BookingWorkflow and the activities it invokes can attach
+// it to their loggers without threading it through every function
+// signature.
+package correlation
+
+import (
+	"context"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/workflow"
+)
+
+// propagationKey is the header key the request ID travels under.
+const propagationKey = "request-id"
+
+type contextKey string
+
+const requestIDContextKey contextKey = "requestID"
+
+// NewPropagator returns a Temporal ContextPropagator to register on both
+// the client.Options used to start/signal workflows (cmd/server) and the
+// client.Options the worker dials with (cmd/worker).
+func NewPropagator() workflow.ContextPropagator {
+	return &requestIDPropagator{}
+}
+
+// WithRequestID stores id on a plain context.Context so Inject picks it up
+// when that context starts or signals a workflow.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, id)
+}
+
+// RequestIDFromContext reads the request ID Extract placed on an
+// activity's context.Context, returning "" if none was propagated.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// WithRequestIDWorkflow stores id on a workflow.Context so
+// InjectFromWorkflow picks it up for activity calls and child workflows
+// started from ctx.
+func WithRequestIDWorkflow(ctx workflow.Context, id string) workflow.Context {
+	return workflow.WithValue(ctx, requestIDContextKey, id)
+}
+
+// RequestIDFromWorkflowContext reads the request ID ExtractToWorkflow
+// placed on a workflow.Context, returning "" if none was propagated.
+func RequestIDFromWorkflowContext(ctx workflow.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+type requestIDPropagator struct{}
+
+func (p *requestIDPropagator) Inject(ctx context.Context, writer workflow.HeaderWriter) error {
+	return setHeader(writer, RequestIDFromContext(ctx))
+}
+
+func (p *requestIDPropagator) InjectFromWorkflow(ctx workflow.Context, writer workflow.HeaderWriter) error {
+	return setHeader(writer, RequestIDFromWorkflowContext(ctx))
+}
+
+func (p *requestIDPropagator) Extract(ctx context.Context, reader workflow.HeaderReader) (context.Context, error) {
+	id, err := readHeader(reader)
+	if err != nil || id == "" {
+		return ctx, err
+	}
+	return WithRequestID(ctx, id), nil
+}
+
+func (p *requestIDPropagator) ExtractToWorkflow(ctx workflow.Context, reader workflow.HeaderReader) (workflow.Context, error) {
+	id, err := readHeader(reader)
+	if err != nil || id == "" {
+		return ctx, err
+	}
+	return WithRequestIDWorkflow(ctx, id), nil
+}
+
+func setHeader(writer workflow.HeaderWriter, id string) error {
+	if id == "" {
+		return nil
+	}
+	payload, err := converter.GetDefaultDataConverter().ToPayload(id)
+	if err != nil {
+		return err
+	}
+	writer.Set(propagationKey, payload)
+	return nil
+}
+
+func readHeader(reader workflow.HeaderReader) (string, error) {
+	var id string
+	err := reader.ForEachKey(func(key string, value *commonpb.Payload) error {
+		if key != propagationKey {
+			return nil
+		}
+		return converter.GetDefaultDataConverter().FromPayload(value, &id)
+	})
+	return id, err
+}
@@ -5,9 +5,12 @@ import (
 	"time"
 
 	"flight-booking-system/internal/config"
+	"flight-booking-system/internal/fsm"
 	"flight-booking-system/internal/models"
 	"flight-booking-system/internal/temporal/activities"
+	"flight-booking-system/internal/temporal/correlation"
 
+	"go.temporal.io/sdk/log"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
@@ -19,15 +22,387 @@ const (
 	QueryGetStatus      = "getStatus"
 )
 
-// BookingWorkflow orchestrates the entire booking lifecycle
+// Booking FSM states. Each one doubles as the EventType that drives the
+// machine into it (see fsm.resolve), so e.g. firing eventConfirmed moves
+// the machine into stateConfirmed.
+const (
+	stateCreated        fsm.State = fsm.State(models.StatusCreated)
+	stateSeatsReserved  fsm.State = fsm.State(models.StatusSeatsReserved)
+	statePaymentPending fsm.State = fsm.State(models.StatusPaymentPending)
+	stateConfirmed      fsm.State = fsm.State(models.StatusConfirmed)
+	stateFailed         fsm.State = fsm.State(models.StatusFailed)
+	stateExpired        fsm.State = fsm.State(models.StatusExpired)
+	stateCancelled      fsm.State = fsm.State(models.StatusCancelled)
+	stateCompensating   fsm.State = fsm.State(models.StatusCompensating)
+	stateRefunded       fsm.State = fsm.State(models.StatusRefunded)
+
+	eventReserve       fsm.EventType = "reserve"
+	eventSeatsReserved fsm.EventType = fsm.EventType(stateSeatsReserved)
+	eventSubmitPayment fsm.EventType = fsm.EventType(statePaymentPending)
+	eventConfirmed     fsm.EventType = fsm.EventType(stateConfirmed)
+	eventFailed        fsm.EventType = fsm.EventType(stateFailed)
+	eventExpired       fsm.EventType = fsm.EventType(stateExpired)
+	eventCancelled     fsm.EventType = fsm.EventType(stateCancelled)
+	eventCompensating  fsm.EventType = fsm.EventType(stateCompensating)
+	eventRefunded      fsm.EventType = fsm.EventType(stateRefunded)
+)
+
+// bookingMachine bundles the FSM together with the state it mutates, so
+// actions registered as closures can reach workflow/activity context
+// without threading it through EventContext.Payload on every call.
+type bookingMachine struct {
+	fsm            *fsm.FSM
+	state          *models.BookingState
+	ctx            workflow.Context
+	activityCtx    workflow.Context
+	seatActivities *activities.SeatActivities
+	orderActs      *activities.OrderActivities
+	paymentActs    *activities.PaymentActivities
+	logger         log.Logger
+	workflowErr    error
+	compensations  []func() error
+	refunded       bool
+}
+
+func newBookingMachine(ctx, activityCtx workflow.Context, state *models.BookingState,
+	seatActivities *activities.SeatActivities, orderActs *activities.OrderActivities,
+	paymentActs *activities.PaymentActivities, logger log.Logger) *bookingMachine {
+
+	bm := &bookingMachine{
+		state:          state,
+		ctx:            ctx,
+		activityCtx:    activityCtx,
+		seatActivities: seatActivities,
+		orderActs:      orderActs,
+		paymentActs:    paymentActs,
+		logger:         logger,
+	}
+
+	transitions := fsm.StateTransitions{
+		stateCreated: {
+			eventReserve: bm.actionReserveSeats,
+		},
+		stateSeatsReserved: {
+			eventSubmitPayment: bm.actionEnterPaymentPending,
+			eventCancelled:     bm.actionRelease(models.StatusCancelled),
+			eventExpired:       bm.actionRelease(models.StatusExpired),
+		},
+		statePaymentPending: {
+			eventConfirmed: bm.actionConfirm,
+			eventFailed:    bm.actionReleaseAfterPaymentFailure,
+		},
+		stateCompensating: {
+			eventFailed:   bm.actionFinishCompensation(models.StatusFailed),
+			eventRefunded: bm.actionFinishCompensation(models.StatusRefunded),
+		},
+	}
+
+	f := fsm.New(stateCreated, transitions,
+		stateConfirmed, stateFailed, stateExpired, stateCancelled, stateRefunded)
+	f.ErrorState = stateFailed
+	// HandleError is the saga coordinator's failure hook: any later step
+	// that fails after an earlier one has registered a compensation (e.g.
+	// ConfirmSeats failing after payment already captured) is routed
+	// through Compensating to unwind what's already been done, instead of
+	// landing straight in Failed with seats or money left stranded.
+	f.HandleError = func(err error, ec fsm.EventContext) fsm.EventType {
+		bm.logger.Error("FSM action failed", "event", ec.Event, "error", err)
+		bm.workflowErr = err
+		if len(bm.compensations) > 0 {
+			return eventCompensating
+		}
+		return eventFailed
+	}
+	f.OnEnter(stateSeatsReserved, func(ec fsm.EventContext) error {
+		state.Status = models.StatusSeatsReserved
+		state.ReservationStartAt = workflow.Now(ctx)
+		workflow.ExecuteActivity(activityCtx, orderActs.UpdateOrderStatus,
+			state.OrderID, models.StatusSeatsReserved).Get(ctx, nil)
+		return nil
+	})
+	f.OnEnter(statePaymentPending, func(ec fsm.EventContext) error {
+		state.Status = models.StatusPaymentPending
+		signal := ec.Payload.(models.SubmitPaymentSignal)
+		return bm.runPaymentValidation(signal.PaymentCode, signal.IdempotencyKey, signal.RequestID)
+	})
+	f.OnEnter(stateConfirmed, func(ec fsm.EventContext) error { state.Status = models.StatusConfirmed; return nil })
+	f.OnEnter(stateFailed, func(ec fsm.EventContext) error { state.Status = models.StatusFailed; return nil })
+	f.OnEnter(stateExpired, func(ec fsm.EventContext) error { state.Status = models.StatusExpired; return nil })
+	f.OnEnter(stateCancelled, func(ec fsm.EventContext) error { state.Status = models.StatusCancelled; return nil })
+	f.OnEnter(stateCompensating, func(ec fsm.EventContext) error {
+		state.Status = models.StatusCompensating
+		workflow.ExecuteActivity(activityCtx, orderActs.UpdateOrderStatus,
+			state.OrderID, models.StatusCompensating).Get(ctx, nil)
+
+		if err := bm.runCompensations(); err != nil {
+			bm.logger.Error("saga compensation failed", "orderID", state.OrderID, "error", err)
+			return bm.fsm.Fire(fsm.EventContext{Event: eventFailed})
+		}
+		if bm.refunded {
+			return bm.fsm.Fire(fsm.EventContext{Event: eventRefunded})
+		}
+		return bm.fsm.Fire(fsm.EventContext{Event: eventFailed})
+	})
+	f.OnEnter(stateRefunded, func(ec fsm.EventContext) error { state.Status = models.StatusRefunded; return nil })
+
+	bm.fsm = f
+	return bm
+}
+
+// pushCompensation records a step that must be undone if a later saga step
+// fails. runCompensations unwinds them in reverse (LIFO) order, so e.g. a
+// payment refund (pushed after ReserveSeats' release) runs before the
+// seats are released.
+func (bm *bookingMachine) pushCompensation(fn func() error) {
+	bm.compensations = append(bm.compensations, fn)
+}
+
+// runCompensations undoes every successfully-completed saga step in
+// reverse order, stopping at (and returning) the first error rather than
+// silently skipping the rest.
+func (bm *bookingMachine) runCompensations() error {
+	for i := len(bm.compensations) - 1; i >= 0; i-- {
+		if err := bm.compensations[i](); err != nil {
+			return err
+		}
+	}
+	bm.compensations = nil
+	return nil
+}
+
+// actionFinishCompensation builds the Compensating -> {Failed,Refunded}
+// action that records the order's final status once compensation has
+// already run.
+func (bm *bookingMachine) actionFinishCompensation(status string) fsm.Action {
+	return func(ec fsm.EventContext) (fsm.EventType, error) {
+		workflow.ExecuteActivity(bm.activityCtx, bm.orderActs.UpdateOrderStatus,
+			bm.state.OrderID, status).Get(bm.ctx, nil)
+		bm.logger.Info("Saga compensation complete", "orderID", bm.state.OrderID, "status", status)
+		return fsm.EventType(status), nil
+	}
+}
+
+// actionReserveSeats is the Created -> SeatsReserved action.
+func (bm *bookingMachine) actionReserveSeats(ec fsm.EventContext) (fsm.EventType, error) {
+	input := ec.Payload.(models.BookingInput)
+	err := workflow.ExecuteActivity(bm.activityCtx, bm.seatActivities.ReserveSeats,
+		input.FlightID, input.Seats, input.OrderID, input.UserID).Get(bm.ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to reserve initial seats: %w", err)
+	}
+	bm.state.Seats = input.Seats
+	bm.pushCompensation(bm.releaseSeats)
+	return eventSeatsReserved, nil
+}
+
+// actionEnterPaymentPending is the SeatsReserved -> PaymentPending action.
+// It only carries the event's payload across the transition; the actual
+// payment validation runs in the PaymentPending entry hook below, once the
+// FSM has committed to the new state.
+func (bm *bookingMachine) actionEnterPaymentPending(ec fsm.EventContext) (fsm.EventType, error) {
+	return eventSubmitPayment, nil
+}
+
+// runPaymentValidation executes the PaymentValidationWorkflow child
+// workflow and feeds its outcome back into the FSM as a Confirmed/Failed
+// event, now that the machine sits in PaymentPending. When the caller
+// supplied an Idempotency-Key, it replaces the run ID in the child
+// workflow's ID so a client retrying a slow SubmitPayment call attaches to
+// the same child workflow instead of starting a second one. requestID, the
+// id of the SubmitPayment HTTP call that triggered this attempt (distinct
+// from the CreateOrder request that started the workflow), is attached to
+// the activity/child-workflow context so AuthorizePayment's logs correlate
+// to this specific attempt.
+func (bm *bookingMachine) runPaymentValidation(paymentCode, idempotencyKey, requestID string) error {
+	paymentCtx := bm.activityCtx
+	logger := bm.logger
+	if requestID != "" {
+		paymentCtx = correlation.WithRequestIDWorkflow(paymentCtx, requestID)
+		logger = log.With(logger, "requestID", requestID)
+	}
+
+	workflow.ExecuteActivity(paymentCtx, bm.orderActs.UpdateOrderStatus,
+		bm.state.OrderID, models.StatusPaymentPending).Get(bm.ctx, nil)
+
+	childWorkflowID := idempotencyKey
+	if childWorkflowID == "" {
+		childWorkflowID = workflow.GetInfo(bm.ctx).WorkflowExecution.RunID
+	}
+	childCtx := workflow.WithChildOptions(paymentCtx, workflow.ChildWorkflowOptions{
+		WorkflowID: bm.state.OrderID + "-payment-" + childWorkflowID,
+	})
+
+	var paymentResult *models.PaymentResult
+	err := workflow.ExecuteChildWorkflow(childCtx, PaymentValidationWorkflow,
+		paymentCode, bm.state.OrderID).Get(bm.ctx, &paymentResult)
+
+	if err == nil && paymentResult.Success {
+		logger.Info("Payment successful", "transactionID", paymentResult.TransactionID)
+		bm.state.PaymentCode = paymentCode
+		bm.state.PaymentTransferID = paymentResult.TransactionID
+		bm.pushCompensation(bm.refundPayment)
+		return bm.fsm.Fire(fsm.EventContext{Event: eventConfirmed})
+	}
+
+	logger.Error("Payment failed", "error", err)
+	return bm.fsm.Fire(fsm.EventContext{Event: eventFailed})
+}
+
+// actionConfirm is the PaymentPending -> Confirmed action: mark seats
+// booked and notify the customer.
+func (bm *bookingMachine) actionConfirm(ec fsm.EventContext) (fsm.EventType, error) {
+	if err := workflow.ExecuteActivity(bm.activityCtx, bm.seatActivities.ConfirmSeats, bm.state.OrderID).Get(bm.ctx, nil); err != nil {
+		// Payment has already been captured at this point (the compensation
+		// stack holds a refund for it), so a failure here must go through
+		// the saga coordinator rather than landing in Failed with the
+		// customer still charged.
+		return "", fmt.Errorf("failed to confirm seats: %w", err)
+	}
+	workflow.ExecuteActivity(bm.activityCtx, bm.orderActs.UpdateOrderStatus,
+		bm.state.OrderID, models.StatusConfirmed).Get(bm.ctx, nil)
+	bm.startNotificationWorkflow()
+	bm.logger.Info("Booking confirmed", "orderID", bm.state.OrderID)
+	return eventConfirmed, nil
+}
+
+// startNotificationWorkflow kicks off NotificationWorkflow as a child
+// workflow once the booking is confirmed. It only waits for the child to
+// start, not to finish -- a channel outage shouldn't hold up an
+// already-confirmed booking, so only a failure to start it at all is
+// logged here.
+func (bm *bookingMachine) startNotificationWorkflow() {
+	childCtx := workflow.WithChildOptions(bm.activityCtx, workflow.ChildWorkflowOptions{
+		WorkflowID: bm.state.OrderID + "-notification",
+	})
+	future := workflow.ExecuteChildWorkflow(childCtx, NotificationWorkflow, bm.state.OrderID, bm.state.UserID)
+	if err := future.GetChildWorkflowExecution().Get(bm.ctx, nil); err != nil {
+		bm.logger.Error("Failed to start notification workflow", "orderID", bm.state.OrderID, "error", err)
+	}
+}
+
+// refundPayment is the compensation paired with a successful payment
+// capture: it reverses the transfer so a later saga step's failure (e.g.
+// ConfirmSeats) doesn't leave the customer charged for a booking that
+// never confirmed.
+func (bm *bookingMachine) refundPayment() error {
+	err := workflow.ExecuteActivity(bm.activityCtx, bm.paymentActs.RefundPayment,
+		bm.state.OrderID, bm.state.PaymentCode, bm.state.PaymentTransferID).Get(bm.ctx, nil)
+	if err != nil {
+		return err
+	}
+	bm.refunded = true
+	return nil
+}
+
+// actionReleaseAfterPaymentFailure is the PaymentPending -> Failed action.
+func (bm *bookingMachine) actionReleaseAfterPaymentFailure(ec fsm.EventContext) (fsm.EventType, error) {
+	if err := bm.releaseSeats(); err != nil {
+		bm.workflowErr = fmt.Errorf("failed to release seats: %w", err)
+		return eventFailed, nil
+	}
+	workflow.ExecuteActivity(bm.activityCtx, bm.orderActs.UpdateOrderStatus,
+		bm.state.OrderID, models.StatusFailed).Get(bm.ctx, nil)
+	bm.logger.Info("Order failed due to payment failure", "orderID", bm.state.OrderID)
+	return eventFailed, nil
+}
+
+// actionRelease builds the SeatsReserved -> {Cancelled,Expired} action for
+// the given terminal status.
+func (bm *bookingMachine) actionRelease(status string) fsm.Action {
+	return func(ec fsm.EventContext) (fsm.EventType, error) {
+		if err := bm.releaseSeats(); err != nil {
+			bm.workflowErr = fmt.Errorf("failed to release seats: %w", err)
+			return fsm.EventType(status), nil
+		}
+		workflow.ExecuteActivity(bm.activityCtx, bm.orderActs.UpdateOrderStatus,
+			bm.state.OrderID, status).Get(bm.ctx, nil)
+		bm.logger.Info("Order reached terminal state", "orderID", bm.state.OrderID, "status", status)
+		return fsm.EventType(status), nil
+	}
+}
+
+func (bm *bookingMachine) releaseSeats() error {
+	if err := workflow.ExecuteActivity(bm.activityCtx, bm.seatActivities.ReleaseSeats, bm.state.OrderID).Get(bm.ctx, nil); err != nil {
+		return err
+	}
+	bm.notifyWaitlist()
+	return nil
+}
+
+// notifyWaitlist tells this flight's waitlist coordinator that seats just
+// came free. It's best-effort: if no one is waiting, the coordinator
+// workflow was never started and the signal simply fails to find it.
+func (bm *bookingMachine) notifyWaitlist() {
+	err := workflow.SignalExternalWorkflow(bm.ctx, WaitlistCoordinatorID(bm.state.FlightID), "",
+		SignalSeatsFreed, SeatsFreedSignal{Seats: bm.state.Seats}).Get(bm.ctx, nil)
+	if err != nil {
+		bm.logger.Info("No waitlist coordinator to notify", "flightID", bm.state.FlightID, "error", err)
+	}
+}
+
+// terminal reports whether the machine has reached a state the workflow
+// should stop looping in.
+func (bm *bookingMachine) terminal() bool {
+	switch bm.fsm.Current {
+	case stateConfirmed, stateFailed, stateExpired, stateCancelled, stateRefunded:
+		return true
+	}
+	return false
+}
+
+// recordTransitionLog persists the FSM's full transition history
+// alongside the order for auditability, once the workflow has reached a
+// terminal state or failed outright. It's best-effort: a failure here is
+// logged but doesn't change the workflow's own outcome, the same way
+// notifyWaitlist's failure to reach a coordinator doesn't fail the
+// booking either.
+func (bm *bookingMachine) recordTransitionLog() {
+	records := bm.fsm.Log()
+	if len(records) == 0 {
+		return
+	}
+
+	entries := make([]models.TransitionLogEntry, len(records))
+	for i, r := range records {
+		entries[i] = models.TransitionLogEntry{
+			OrderID:   bm.state.OrderID,
+			Seq:       r.Seq,
+			FromState: string(r.From),
+			ToState:   string(r.To),
+			Event:     string(r.Event),
+		}
+	}
+
+	err := workflow.ExecuteActivity(bm.activityCtx, bm.orderActs.RecordTransitionLog, entries).Get(bm.ctx, nil)
+	if err != nil {
+		bm.logger.Error("Failed to record transition log", "orderID", bm.state.OrderID, "error", err)
+	}
+}
+
+// BookingWorkflow orchestrates the entire booking lifecycle on top of the
+// declarative FSM in internal/fsm: states accept events and run an Action,
+// and the FSM records a transition log for auditability alongside the
+// order. Adding a new state (e.g. Waitlisted) is a change to the
+// transitions table above rather than another branch in this loop.
+//
+// bookingMachine doubles as a saga coordinator: actions that complete a
+// step with a compensating undo (ReserveSeats -> ReleaseSeats, payment
+// capture -> refund) register it via pushCompensation, and the FSM's
+// HandleError routes any later failure through Compensating, which runs
+// them in reverse before landing in Failed or Refunded.
 func BookingWorkflow(ctx workflow.Context, input models.BookingInput) (*models.BookingResult, error) {
-	logger := workflow.GetLogger(ctx)
-	logger.Info("BookingWorkflow started", "orderID", input.OrderID)
+	// Stash the request ID on ctx so correlation.NewPropagator forwards it
+	// into every activity and child workflow started from ctx (and its
+	// descendants, e.g. activityCtx below), and build a session logger that
+	// attaches it alongside the order/flight/user IDs to every log line.
+	ctx = correlation.WithRequestIDWorkflow(ctx, input.RequestID)
+	logger := log.With(workflow.GetLogger(ctx),
+		"requestID", input.RequestID, "orderID", input.OrderID,
+		"flightID", input.FlightID, "userID", input.UserID)
+	logger.Info("BookingWorkflow started")
 
-	// Load configuration
 	cfg := config.Load()
 
-	// Initialize workflow state
 	state := &models.BookingState{
 		OrderID:            input.OrderID,
 		FlightID:           input.FlightID,
@@ -37,7 +412,6 @@ func BookingWorkflow(ctx workflow.Context, input models.BookingInput) (*models.B
 		ReservationStartAt: workflow.Now(ctx),
 	}
 
-	// Set up activity options
 	activityOptions := workflow.ActivityOptions{
 		StartToCloseTimeout: 30 * time.Second,
 		RetryPolicy: &temporal.RetryPolicy{
@@ -49,8 +423,6 @@ func BookingWorkflow(ctx workflow.Context, input models.BookingInput) (*models.B
 	}
 	activityCtx := workflow.WithActivityOptions(ctx, activityOptions)
 
-	// Set up query handler for real-time status
-	// yuvald TODO  explain
 	err := workflow.SetQueryHandler(ctx, QueryGetStatus, func() (*models.BookingState, error) {
 		// NOTE: We don't calculate TimeRemaining here because workflow.Now(ctx) returns
 		// deterministic time that doesn't advance during idle periods. The server calculates
@@ -61,51 +433,41 @@ func BookingWorkflow(ctx workflow.Context, input models.BookingInput) (*models.B
 		return nil, err
 	}
 
-	// Set up signal channels
 	seatUpdateChan := workflow.GetSignalChannel(ctx, SignalUpdateSeats)
 	paymentChan := workflow.GetSignalChannel(ctx, SignalSubmitPayment)
 	cancelChan := workflow.GetSignalChannel(ctx, SignalCancelOrder)
 
-	// Reserve initial seats
 	var seatActivities *activities.SeatActivities
-	err = workflow.ExecuteActivity(activityCtx, seatActivities.ReserveSeats,
-		input.FlightID, input.Seats, input.OrderID, input.UserID).Get(ctx, nil)
-	if err != nil {
+	var orderActivities *activities.OrderActivities
+	var paymentActivities *activities.PaymentActivities
+	bm := newBookingMachine(ctx, activityCtx, state, seatActivities, orderActivities, paymentActivities, logger)
+
+	if err := bm.fsm.Fire(fsm.EventContext{Event: eventReserve, Payload: input}); err != nil || bm.workflowErr != nil {
+		if err == nil {
+			// HandleError already moved the FSM to Failed and kept the cause.
+			err = bm.workflowErr
+		}
 		logger.Error("Failed to reserve initial seats", "error", err)
 		state.Status = models.StatusFailed
+		bm.recordTransitionLog()
 		return &models.BookingResult{State: state}, err
 	}
 
-	state.Seats = input.Seats
-	state.Status = models.StatusSeatsReserved
-	state.ReservationStartAt = workflow.Now(ctx)
-
-	// Update order status
-	var orderActivities *activities.OrderActivities
-	workflow.ExecuteActivity(activityCtx, orderActivities.UpdateOrderStatus,
-		input.OrderID, models.StatusSeatsReserved).Get(ctx, nil)
-
 	// Start reservation timer
 	timerCtx, cancelTimer := workflow.WithCancel(ctx)
 	timerFuture := workflow.NewTimer(timerCtx, cfg.ReservationTimeout)
 
-	//explain all temporal idioms
-
-	// Main event loop
-	var workflowErr error
 	for {
 		selector := workflow.NewSelector(ctx)
 
-		// Handle seat updates
 		selector.AddReceive(seatUpdateChan, func(c workflow.ReceiveChannel, more bool) {
 			var newSeats []string
 			c.Receive(ctx, &newSeats)
 
 			logger.Info("Received seat update signal", "newSeats", newSeats)
 
-			// Update seats
 			err := workflow.ExecuteActivity(activityCtx, seatActivities.UpdateSeats,
-				state.OrderID, newSeats).Get(ctx, nil)
+				state.OrderID, state.Seats, newSeats).Get(ctx, nil)
 			if err != nil {
 				logger.Error("Failed to update seats", "error", err)
 				return
@@ -114,7 +476,6 @@ func BookingWorkflow(ctx workflow.Context, input models.BookingInput) (*models.B
 			state.Seats = newSeats
 			state.ReservationStartAt = workflow.Now(ctx)
 
-			// Cancel old timer and start new one
 			cancelTimer()
 			timerCtx, cancelTimer = workflow.WithCancel(ctx)
 			timerFuture = workflow.NewTimer(timerCtx, cfg.ReservationTimeout)
@@ -122,134 +483,54 @@ func BookingWorkflow(ctx workflow.Context, input models.BookingInput) (*models.B
 			logger.Info("Seat update complete, timer reset", "newSeats", newSeats)
 		})
 
-		// Handle payment submission
 		selector.AddReceive(paymentChan, func(c workflow.ReceiveChannel, more bool) {
-			var paymentCode string
-			c.Receive(ctx, &paymentCode)
-
-			logger.Info("Received payment signal", "paymentCode", paymentCode)
-
-			state.Status = models.StatusPaymentPending
-			workflow.ExecuteActivity(activityCtx, orderActivities.UpdateOrderStatus,
-				state.OrderID, models.StatusPaymentPending).Get(ctx, nil)
-
-			// Execute payment validation child workflow
-			// Use workflow run ID to ensure unique child workflow ID even if order is retried
-			workflowInfo := workflow.GetInfo(ctx)
-			childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
-				WorkflowID: state.OrderID + "-payment-" + workflowInfo.WorkflowExecution.RunID,
-			})
-
-			var paymentResult *models.PaymentResult
-			err := workflow.ExecuteChildWorkflow(childCtx, PaymentValidationWorkflow,
-				paymentCode, state.OrderID).Get(ctx, &paymentResult)
-
-			if err == nil && paymentResult.Success {
-				logger.Info("Payment successful", "transactionID", paymentResult.TransactionID)
-
-				state.Status = models.StatusConfirmed
-
-				// Confirm seats (mark as BOOKED)
-				workflow.ExecuteActivity(activityCtx, seatActivities.ConfirmSeats, state.OrderID).Get(ctx, nil)
+			var signal models.SubmitPaymentSignal
+			c.Receive(ctx, &signal)
 
-				// Update order status
-				workflow.ExecuteActivity(activityCtx, orderActivities.UpdateOrderStatus,
-					state.OrderID, models.StatusConfirmed).Get(ctx, nil)
+			logger.Info("Received payment signal", "paymentCode", signal.PaymentCode)
 
-				// Send confirmation
-				workflow.ExecuteActivity(activityCtx, orderActivities.SendConfirmation,
-					state.OrderID).Get(ctx, nil)
-
-				logger.Info("Booking confirmed", "orderID", state.OrderID)
-			} else {
-				logger.Error("Payment failed", "error", err)
-
-				state.Status = models.StatusFailed
-
-				// Release seats - fail workflow if this fails to prevent data inconsistency
-				releaseErr := workflow.ExecuteActivity(activityCtx, seatActivities.ReleaseSeats, state.OrderID).Get(ctx, nil)
-				if releaseErr != nil {
-					logger.Error("Failed to release seats after payment failure", "error", releaseErr)
-					workflowErr = fmt.Errorf("failed to release seats: %w", releaseErr)
-					return
-				}
-
-				// Update order status
-				workflow.ExecuteActivity(activityCtx, orderActivities.UpdateOrderStatus,
-					state.OrderID, models.StatusFailed).Get(ctx, nil)
-
-				logger.Info("Order failed due to payment failure", "orderID", state.OrderID)
+			if err := bm.fsm.Fire(fsm.EventContext{Event: eventSubmitPayment, Payload: signal}); err != nil {
+				bm.workflowErr = err
 			}
 		})
 
-		// Handle cancellation
 		selector.AddReceive(cancelChan, func(c workflow.ReceiveChannel, more bool) {
 			var cancel bool
 			c.Receive(ctx, &cancel)
 
 			logger.Info("Received cancel signal", "orderID", state.OrderID)
 
-			state.Status = models.StatusCancelled
-
-			// Release seats - fail workflow if this fails to prevent data inconsistency
-			releaseErr := workflow.ExecuteActivity(activityCtx, seatActivities.ReleaseSeats, state.OrderID).Get(ctx, nil)
-			if releaseErr != nil {
-				logger.Error("Failed to release seats after cancellation", "error", releaseErr)
-				workflowErr = fmt.Errorf("failed to release seats: %w", releaseErr)
-				return
+			if err := bm.fsm.Fire(fsm.EventContext{Event: eventCancelled}); err != nil {
+				bm.workflowErr = err
 			}
-
-			// Update order status
-			workflow.ExecuteActivity(activityCtx, orderActivities.UpdateOrderStatus,
-				state.OrderID, models.StatusCancelled).Get(ctx, nil)
-
-			logger.Info("Order cancelled", "orderID", state.OrderID)
 		})
 
-		// Handle timer expiration
 		selector.AddFuture(timerFuture, func(f workflow.Future) {
 			err := f.Get(ctx, nil)
 			if err != nil {
-				// Timer was cancelled (likely due to seat update)
 				logger.Info("Timer cancelled")
 				return
 			}
 
 			logger.Info("Reservation timer expired", "orderID", state.OrderID)
 
-			state.Status = models.StatusExpired
-
-			// Release seats - fail workflow if this fails to prevent data inconsistency
-			releaseErr := workflow.ExecuteActivity(activityCtx, seatActivities.ReleaseSeats, state.OrderID).Get(ctx, nil)
-			if releaseErr != nil {
-				logger.Error("Failed to release seats after expiration", "error", releaseErr)
-				workflowErr = fmt.Errorf("failed to release seats: %w", releaseErr)
-				return
+			if err := bm.fsm.Fire(fsm.EventContext{Event: eventExpired}); err != nil {
+				bm.workflowErr = err
 			}
-
-			// Update order status
-			workflow.ExecuteActivity(activityCtx, orderActivities.UpdateOrderStatus,
-				state.OrderID, models.StatusExpired).Get(ctx, nil)
-
-			logger.Info("Order expired", "orderID", state.OrderID)
 		})
 
 		selector.Select(ctx)
 
-		// Exit conditions
-		if state.Status == models.StatusConfirmed ||
-			state.Status == models.StatusFailed ||
-			state.Status == models.StatusExpired ||
-			state.Status == models.StatusCancelled ||
-			workflowErr != nil {
+		if bm.terminal() || bm.workflowErr != nil {
 			break
 		}
 	}
 
-	// Check if workflow failed due to seat release error
-	if workflowErr != nil {
-		logger.Error("BookingWorkflow failed", "orderID", input.OrderID, "error", workflowErr)
-		return &models.BookingResult{State: state}, workflowErr
+	bm.recordTransitionLog()
+
+	if bm.workflowErr != nil {
+		logger.Error("BookingWorkflow failed", "orderID", input.OrderID, "error", bm.workflowErr)
+		return &models.BookingResult{State: state}, bm.workflowErr
 	}
 
 	logger.Info("BookingWorkflow completed", "orderID", input.OrderID, "status", state.Status)
@@ -0,0 +1,81 @@
+package workflows
+
+import (
+	"time"
+
+	"flight-booking-system/internal/models"
+	"flight-booking-system/internal/temporal/activities"
+	"flight-booking-system/internal/temporal/correlation"
+
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const QueryGetMultiStatus = "getMultiStatus"
+
+// MultiFlightBookingWorkflow books an itinerary spanning one or more
+// flight legs atomically, using two-phase commit across the legs instead
+// of the single-flight FSM that drives BookingWorkflow: a single
+// Temporal workflow already gets atomicity for free within one flight's
+// seats, but legs on independent flights need an explicit vote/decide
+// protocol to get the same all-or-nothing guarantee across them.
+//
+// The itinerary ID doubles as the 2PC transaction ID, since this workflow
+// coordinates exactly one transaction over its lifetime.
+func MultiFlightBookingWorkflow(ctx workflow.Context, input models.MultiFlightBookingInput) (*models.MultiFlightBookingResult, error) {
+	ctx = correlation.WithRequestIDWorkflow(ctx, input.RequestID)
+	logger := log.With(workflow.GetLogger(ctx),
+		"requestID", input.RequestID, "itineraryID", input.ItineraryID, "userID", input.UserID)
+	logger.Info("MultiFlightBookingWorkflow started", "legs", len(input.Legs))
+
+	txnID := input.ItineraryID
+
+	state := &models.MultiFlightBookingState{
+		ItineraryID: input.ItineraryID,
+		UserID:      input.UserID,
+		Legs:        input.Legs,
+		Status:      models.StatusCreated,
+	}
+
+	err := workflow.SetQueryHandler(ctx, QueryGetMultiStatus, func() (*models.MultiFlightBookingState, error) {
+		return state, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	activityCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    30 * time.Second,
+			MaximumAttempts:    5,
+		},
+	})
+
+	var twoPhase *activities.TwoPhaseActivities
+
+	var prepared []models.TransactionLogEntry
+	prepErr := workflow.ExecuteActivity(activityCtx, twoPhase.PrepareReservation,
+		txnID, input.ItineraryID, input.UserID, input.Legs).Get(ctx, &prepared)
+	if prepErr != nil {
+		logger.Error("2PC prepare failed, voting to abort", "error", prepErr, "preparedLegs", len(prepared))
+		if err := workflow.ExecuteActivity(activityCtx, twoPhase.Abort, txnID).Get(ctx, nil); err != nil {
+			logger.Error("2PC abort failed", "error", err)
+		}
+		state.Status = models.StatusFailed
+		return &models.MultiFlightBookingResult{State: state}, prepErr
+	}
+
+	if err := workflow.ExecuteActivity(activityCtx, twoPhase.Commit, txnID).Get(ctx, nil); err != nil {
+		logger.Error("2PC commit failed", "error", err)
+		state.Status = models.StatusFailed
+		return &models.MultiFlightBookingResult{State: state}, err
+	}
+
+	state.Status = models.StatusSeatsReserved
+	logger.Info("MultiFlightBookingWorkflow committed all legs")
+	return &models.MultiFlightBookingResult{State: state}, nil
+}
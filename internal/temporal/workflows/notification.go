@@ -0,0 +1,57 @@
+package workflows
+
+import (
+	"time"
+
+	"flight-booking-system/internal/temporal/activities"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// NotificationWorkflow fans a booking confirmation out across every
+// notification channel in parallel. A channel failing (after its own
+// activity retries are exhausted) doesn't fail the others or this
+// workflow -- BookingWorkflow doesn't wait on notification delivery to
+// consider the booking confirmed, so a channel outage shouldn't be able
+// to block it.
+func NotificationWorkflow(ctx workflow.Context, orderID, userID string) error {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("NotificationWorkflow started", "orderID", orderID)
+
+	activityOptions := workflow.ActivityOptions{
+		StartToCloseTimeout: 15 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    15 * time.Second,
+			MaximumAttempts:    3,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+
+	var notificationActivities *activities.NotificationActivities
+
+	channels := []struct {
+		name string
+		fn   interface{}
+	}{
+		{"email", notificationActivities.SendEmailConfirmation},
+		{"sms", notificationActivities.SendSMSConfirmation},
+		{"webhook", notificationActivities.SendWebhookConfirmation},
+	}
+
+	futures := make([]workflow.Future, len(channels))
+	for i, ch := range channels {
+		futures[i] = workflow.ExecuteActivity(ctx, ch.fn, orderID, userID)
+	}
+
+	for i, future := range futures {
+		if err := future.Get(ctx, nil); err != nil {
+			logger.Error("Notification channel failed", "channel", channels[i].name, "error", err)
+		}
+	}
+
+	logger.Info("NotificationWorkflow completed", "orderID", orderID)
+	return nil
+}
@@ -5,13 +5,24 @@ import (
 	"time"
 
 	"flight-booking-system/internal/models"
+	"flight-booking-system/internal/payments"
 	"flight-booking-system/internal/temporal/activities"
 
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
 
-// PaymentValidationWorkflow validates payment with retries
+const (
+	paymentPollInitialInterval = 2 * time.Second
+	paymentPollBackoffFactor   = 2.0
+	paymentPollMaxInterval     = 30 * time.Second
+	paymentPollTimeout         = 5 * time.Minute
+)
+
+// PaymentValidationWorkflow authorizes orderID's paymentCode through the
+// worker's configured payments.Provider, polling GetTransferStatus with
+// exponential backoff if the provider settles asynchronously, then
+// captures the transfer once it reaches payments.StatusSuccess.
 func PaymentValidationWorkflow(ctx workflow.Context, paymentCode string, orderID string) (*models.PaymentResult, error) {
 	logger := workflow.GetLogger(ctx)
 	logger.Info("PaymentValidationWorkflow started", "orderID", orderID)
@@ -29,39 +40,87 @@ func PaymentValidationWorkflow(ctx workflow.Context, paymentCode string, orderID
 	ctx = workflow.WithActivityOptions(ctx, activityOptions)
 
 	var paymentActivities *activities.PaymentActivities
-	var result *models.PaymentResult
-
-	// Try to validate payment (with automatic retries)
-	err := workflow.ExecuteActivity(ctx, paymentActivities.ValidatePayment, paymentCode, orderID).Get(ctx, &result)
-	if err != nil {
-		logger.Error("Payment validation failed after retries", "error", err)
-		errorMsg := fmt.Sprintf("payment validation failed: %v", err)
-
-		// Record payment failure
-		workflow.ExecuteActivity(ctx, paymentActivities.UpdatePaymentRecord,
-			orderID, paymentCode, "FAILED", nil, &errorMsg).Get(ctx, nil)
-
-		return &models.PaymentResult{
-			Success:      false,
-			ErrorMessage: errorMsg,
-		}, err
+
+	var transfer *payments.TransferResult
+	if err := workflow.ExecuteActivity(ctx, paymentActivities.AuthorizePayment, orderID, paymentCode).Get(ctx, &transfer); err != nil {
+		logger.Error("Payment authorization failed after retries", "error", err)
+		return failPayment(ctx, paymentActivities, orderID, paymentCode, "", "",
+			fmt.Sprintf("payment authorization failed: %v", err))
+	}
+
+	if transfer.Status == payments.StatusPending {
+		polled, err := pollUntilTerminal(ctx, paymentActivities, transfer.TransferID)
+		if err != nil {
+			logger.Error("Payment status polling failed", "error", err)
+			return failPayment(ctx, paymentActivities, orderID, paymentCode, transfer.ProviderName, transfer.TransferID,
+				fmt.Sprintf("payment polling failed: %v", err))
+		}
+		transfer = polled
+	}
+
+	if transfer.Status != payments.StatusSuccess {
+		logger.Error("Payment authorization unsuccessful", "errorMessage", transfer.ErrorMessage)
+		return failPayment(ctx, paymentActivities, orderID, paymentCode, transfer.ProviderName, transfer.TransferID, transfer.ErrorMessage)
+	}
+
+	var captured *payments.TransferResult
+	if err := workflow.ExecuteActivity(ctx, paymentActivities.CapturePayment, orderID, paymentCode, transfer.TransferID).Get(ctx, &captured); err != nil {
+		logger.Error("Payment capture failed", "error", err)
+		return failPayment(ctx, paymentActivities, orderID, paymentCode, transfer.ProviderName, transfer.TransferID,
+			fmt.Sprintf("payment capture failed: %v", err))
 	}
 
-	if !result.Success {
-		logger.Error("Payment validation unsuccessful", "errorMessage", result.ErrorMessage)
+	logger.Info("Payment captured", "transferID", captured.TransferID)
 
-		// Record payment failure
-		workflow.ExecuteActivity(ctx, paymentActivities.UpdatePaymentRecord,
-			orderID, paymentCode, "FAILED", nil, &result.ErrorMessage).Get(ctx, nil)
+	workflow.ExecuteActivity(ctx, paymentActivities.UpdatePaymentRecord,
+		orderID, paymentCode, captured.ProviderName, "SUCCESS", &captured.TransferID, (*string)(nil)).Get(ctx, nil)
+
+	return &models.PaymentResult{Success: true, TransactionID: captured.TransferID}, nil
+}
+
+// pollUntilTerminal polls GetTransferStatus for transferID with exponential
+// backoff (paymentPollInitialInterval, factor paymentPollBackoffFactor,
+// capped at paymentPollMaxInterval) until the provider reports a terminal
+// status, or gives up once paymentPollTimeout has elapsed.
+func pollUntilTerminal(ctx workflow.Context, paymentActivities *activities.PaymentActivities, transferID string) (*payments.TransferResult, error) {
+	deadline := workflow.Now(ctx).Add(paymentPollTimeout)
+	interval := paymentPollInitialInterval
+
+	for {
+		var transfer *payments.TransferResult
+		if err := workflow.ExecuteActivity(ctx, paymentActivities.GetTransferStatus, transferID).Get(ctx, &transfer); err != nil {
+			return nil, err
+		}
+		if transfer.Terminal() {
+			return transfer, nil
+		}
 
-		return result, fmt.Errorf("payment validation failed: %s", result.ErrorMessage)
+		if workflow.Now(ctx).Add(interval).After(deadline) {
+			return &payments.TransferResult{ProviderName: transfer.ProviderName, TransferID: transferID, Status: payments.StatusFailed,
+				ErrorMessage: "payment polling timed out"}, nil
+		}
+
+		workflow.Sleep(ctx, interval)
+
+		interval = time.Duration(float64(interval) * paymentPollBackoffFactor)
+		if interval > paymentPollMaxInterval {
+			interval = paymentPollMaxInterval
+		}
 	}
+}
 
-	logger.Info("Payment validation successful", "transactionID", result.TransactionID)
+// failPayment records a FAILED payment, including the provider's transfer
+// reference when one was ever assigned, and returns the FAILED result
+// paired with an error.
+func failPayment(ctx workflow.Context, paymentActivities *activities.PaymentActivities, orderID, paymentCode, provider, transferID, errorMessage string) (*models.PaymentResult, error) {
+	var transferIDPtr *string
+	if transferID != "" {
+		transferIDPtr = &transferID
+	}
 
-	// Record payment success
 	workflow.ExecuteActivity(ctx, paymentActivities.UpdatePaymentRecord,
-		orderID, paymentCode, "SUCCESS", &result.TransactionID, nil).Get(ctx, nil)
+		orderID, paymentCode, provider, "FAILED", transferIDPtr, &errorMessage).Get(ctx, nil)
 
-	return result, nil
+	return &models.PaymentResult{Success: false, TransactionID: transferID, ErrorMessage: errorMessage},
+		fmt.Errorf("payment validation failed: %s", errorMessage)
 }
@@ -0,0 +1,243 @@
+package workflows
+
+import (
+	"time"
+
+	"flight-booking-system/internal/models"
+	"flight-booking-system/internal/temporal/activities"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	SignalWaitlistJoin  = "waitlistJoin"
+	SignalWaitlistLeave = "waitlistLeave"
+	SignalSeatsFreed    = "seatsFreed"
+	QueryWaitlistStatus = "waitlistStatus"
+	QueryWaitlistQueue  = "waitlistQueue"
+
+	// waitlistSignalLimit bounds how much workflow history a coordinator
+	// accumulates: after handling this many signals in one run, it
+	// continues-as-new, carrying its queue and promotions over.
+	waitlistSignalLimit = 500
+)
+
+// SeatsFreedSignal is the payload of SignalSeatsFreed: the specific seats
+// that just became AVAILABLE on this flight (e.g. via ReleaseSeats), sent
+// both by BookingWorkflow releasing its own seats and by anything else
+// that frees seats directly against the database (e.g.
+// database.ReservationSweeper, from outside a workflow).
+type SeatsFreedSignal struct {
+	Seats []string `json:"seats"`
+}
+
+// WaitlistCoordinatorID returns the well-known workflow ID for a flight's
+// waitlist coordinator, used both to start it and to signal it.
+func WaitlistCoordinatorID(flightID string) string {
+	return "waitlist-coordinator-" + flightID
+}
+
+// WaitlistCoordinatorWorkflow is a long-running, signal-driven workflow --
+// one per flight, workflow ID "waitlist-coordinator-<flightID>" -- that
+// holds the FIFO queue of users waiting for seats to free up. It never
+// touches the database itself for queue state; the queue lives in
+// workflow state so it survives worker restarts the same way any other
+// Temporal workflow does.
+func WaitlistCoordinatorWorkflow(ctx workflow.Context, flightID string, queue []models.WaitlistEntry, promoted map[string]string) error {
+	logger := workflow.GetLogger(ctx)
+	if queue == nil {
+		queue = []models.WaitlistEntry{}
+	}
+	if promoted == nil {
+		promoted = map[string]string{}
+	}
+
+	err := workflow.SetQueryHandler(ctx, QueryWaitlistStatus, func(entryID string) (*models.WaitlistStatusResponse, error) {
+		if orderID, ok := promoted[entryID]; ok {
+			return &models.WaitlistStatusResponse{EntryID: entryID, PromotedOrderID: orderID}, nil
+		}
+		for i, e := range queue {
+			if e.EntryID == entryID {
+				return &models.WaitlistStatusResponse{
+					EntryID:              entryID,
+					Position:             i + 1,
+					EstimatedWaitSeconds: int64(i+1) * 10 * 60, // naive: ~10 minutes per entry up to and including this one
+				}, nil
+			}
+		}
+		return &models.WaitlistStatusResponse{EntryID: entryID}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = workflow.SetQueryHandler(ctx, QueryWaitlistQueue, func() ([]models.WaitlistEntry, error) {
+		return queue, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	joinChan := workflow.GetSignalChannel(ctx, SignalWaitlistJoin)
+	leaveChan := workflow.GetSignalChannel(ctx, SignalWaitlistLeave)
+	freedChan := workflow.GetSignalChannel(ctx, SignalSeatsFreed)
+
+	activityCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+	})
+
+	for handled := 0; ; handled++ {
+		if handled >= waitlistSignalLimit {
+			return workflow.NewContinueAsNewError(ctx, WaitlistCoordinatorWorkflow, flightID, queue, promoted)
+		}
+
+		selector := workflow.NewSelector(ctx)
+
+		selector.AddReceive(joinChan, func(c workflow.ReceiveChannel, more bool) {
+			var entry models.WaitlistEntry
+			c.Receive(ctx, &entry)
+			entry.JoinedAt = workflow.Now(ctx)
+			queue = append(queue, entry)
+			logger.Info("Waitlist join", "entryID", entry.EntryID, "flightID", flightID, "position", len(queue))
+		})
+
+		selector.AddReceive(leaveChan, func(c workflow.ReceiveChannel, more bool) {
+			var entryID string
+			c.Receive(ctx, &entryID)
+			for i, e := range queue {
+				if e.EntryID == entryID {
+					queue = append(queue[:i], queue[i+1:]...)
+					break
+				}
+			}
+			logger.Info("Waitlist leave", "entryID", entryID, "flightID", flightID)
+		})
+
+		selector.AddReceive(freedChan, func(c workflow.ReceiveChannel, more bool) {
+			var signal SeatsFreedSignal
+			c.Receive(ctx, &signal)
+			queue = promoteEligible(ctx, activityCtx, flightID, queue, signal.Seats, promoted, logger)
+		})
+
+		selector.Select(ctx)
+	}
+}
+
+// promoteEligible pops FIFO entries that the freed seats can satisfy --
+// respecting both seat count and, when an entry named specific
+// PreferredSeats, that those exact seats are among what's free -- and
+// promotes each one into its own BookingWorkflow, which reuses the
+// existing reservation-timeout window as the waitlist's acceptance window
+// (e.g. 10-15 minutes to complete payment) -- no separate hold-timer
+// machinery needed. It stops at the first entry the freed seats can't
+// satisfy, same as the plain seat-count case: the queue stays strictly
+// FIFO rather than skipping ahead to a later entry that could be granted.
+func promoteEligible(ctx, activityCtx workflow.Context, flightID string, queue []models.WaitlistEntry,
+	freedSeats []string, promoted map[string]string, logger interface {
+		Info(string, ...interface{})
+		Error(string, ...interface{})
+	}) []models.WaitlistEntry {
+
+	remaining := append([]string{}, freedSeats...)
+
+	for len(queue) > 0 && len(remaining) > 0 {
+		entry := queue[0]
+		grant, ok := allocateSeats(entry, remaining)
+		if !ok {
+			break
+		}
+
+		remaining = removeSeats(remaining, grant)
+		queue = queue[1:]
+
+		orderID := uuid.New().String()
+		input := models.BookingInput{
+			OrderID:  orderID,
+			FlightID: flightID,
+			UserID:   entry.UserID,
+			Seats:    grant,
+		}
+
+		childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+			WorkflowID: orderID,
+		})
+		future := workflow.ExecuteChildWorkflow(childCtx, BookingWorkflow, input)
+		if err := future.GetChildWorkflowExecution().Get(ctx, nil); err != nil {
+			logger.Error("Failed to start promoted waitlist booking", "entryID", entry.EntryID, "error", err)
+			continue
+		}
+
+		var orderActivities *activities.OrderActivities
+		order := &models.Order{
+			OrderID:    orderID,
+			FlightID:   flightID,
+			UserID:     entry.UserID,
+			Status:     models.StatusCreated,
+			WorkflowID: orderID,
+		}
+		if err := workflow.ExecuteActivity(activityCtx, orderActivities.CreateOrder, order).Get(ctx, nil); err != nil {
+			logger.Error("Failed to persist promoted waitlist order", "entryID", entry.EntryID, "error", err)
+			continue
+		}
+
+		promoted[entry.EntryID] = orderID
+		logger.Info("Promoted waitlist entry", "entryID", entry.EntryID, "orderID", orderID, "seats", grant)
+
+		// Promotion fires the child BookingWorkflow and moves on; the
+		// coordinator doesn't block the queue on this user completing
+		// payment. If they let the reservation expire, BookingWorkflow's
+		// own timeout releases the seats and a future SeatsFreed signal
+		// reaches the next entry in line.
+	}
+
+	return queue
+}
+
+// allocateSeats picks entry.SeatsRequested seats to grant entry out of
+// available. An entry with no PreferredSeats takes the first N available;
+// one that named preferences only takes seats from that list, so it never
+// gets bumped into a window seat it didn't ask for. ok is false if
+// available can't satisfy the request at all.
+func allocateSeats(entry models.WaitlistEntry, available []string) (grant []string, ok bool) {
+	if entry.SeatsRequested > len(available) {
+		return nil, false
+	}
+
+	if len(entry.PreferredSeats) == 0 {
+		return append([]string{}, available[:entry.SeatsRequested]...), true
+	}
+
+	availableSet := make(map[string]bool, len(available))
+	for _, seat := range available {
+		availableSet[seat] = true
+	}
+
+	for _, seat := range entry.PreferredSeats {
+		if !availableSet[seat] {
+			continue
+		}
+		grant = append(grant, seat)
+		if len(grant) == entry.SeatsRequested {
+			return grant, true
+		}
+	}
+
+	return nil, false
+}
+
+// removeSeats returns available with every seat in taken removed.
+func removeSeats(available, taken []string) []string {
+	takenSet := make(map[string]bool, len(taken))
+	for _, seat := range taken {
+		takenSet[seat] = true
+	}
+
+	remaining := make([]string, 0, len(available)-len(taken))
+	for _, seat := range available {
+		if !takenSet[seat] {
+			remaining = append(remaining, seat)
+		}
+	}
+	return remaining
+}